@@ -6,6 +6,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
 )
 
 // CircuitBreaker is created for each ExecutorPool to track whether requests
@@ -17,9 +19,14 @@ type CircuitBreaker struct {
 	forceOpen              bool
 	mutex                  *sync.RWMutex
 	openedOrLastTestedTime int64
+	closedTime             int64
+	failedProbes           int32
+	sleepWindow            int64
 
 	executorPool *bufferedExecutorPool
 	metrics      *metricExchange
+	// done stops the periodic state updates, it is nil when they are disabled
+	done chan struct{}
 }
 
 var (
@@ -62,8 +69,13 @@ func Flush() {
 	for name, cb := range circuitBreakers {
 		cb.metrics.Reset()
 		cb.executorPool.Metrics.Reset()
+		cb.metrics.Stop()
+		if cb.done != nil {
+			close(cb.done)
+		}
 		delete(circuitBreakers, name)
 	}
+	flushRateLimiters()
 }
 
 // newCircuitBreaker creates a CircuitBreaker with associated Health
@@ -76,6 +88,11 @@ func newCircuitBreaker(name string) *CircuitBreaker {
 	c.executorPool = newBufferedExecutorPool(name)
 	c.mutex = &sync.RWMutex{}
 
+	if interval := getSettings(name).MetricsStateInterval; interval > 0 {
+		c.done = make(chan struct{})
+		go c.reportStateEvery(interval)
+	}
+
 	return c
 }
 
@@ -87,7 +104,10 @@ func (circuit *CircuitBreaker) toggleForceOpen(toggle bool) error {
 		return err
 	}
 
+	circuit.mutex.Lock()
 	circuit.forceOpen = toggle
+	circuit.metrics.updateState(circuit.stateLocked())
+	circuit.mutex.Unlock()
 	return nil
 }
 
@@ -119,7 +139,18 @@ func (circuit *CircuitBreaker) IsOpen() bool {
 // When the circuit is open, this call will occasionally return true to measure whether the external service
 // has recovered.
 func (circuit *CircuitBreaker) AllowRequest() bool {
-	return !circuit.IsOpen() || circuit.allowSingleTest()
+	allowed, _ := circuit.allowRequest()
+	return allowed
+}
+
+// allowRequest is like AllowRequest, also reporting whether the request is a single test of an open circuit.
+func (circuit *CircuitBreaker) allowRequest() (allowed bool, probe bool) {
+	if !circuit.IsOpen() {
+		return true, false
+	}
+
+	probe = circuit.allowSingleTest()
+	return probe, probe
 }
 
 func (circuit *CircuitBreaker) allowSingleTest() bool {
@@ -128,7 +159,7 @@ func (circuit *CircuitBreaker) allowSingleTest() bool {
 
 	now := time.Now().UnixNano()
 	openedOrLastTestedTime := atomic.LoadInt64(&circuit.openedOrLastTestedTime)
-	if circuit.open && now > openedOrLastTestedTime+getSettings(circuit.Name).SleepWindow.Nanoseconds() {
+	if circuit.open && now > openedOrLastTestedTime+circuit.EffectiveSleepWindow().Nanoseconds() {
 		swapped := atomic.CompareAndSwapInt64(&circuit.openedOrLastTestedTime, openedOrLastTestedTime, now)
 		if swapped {
 			log.Printf("hystrix-go: allowing single test to possibly close circuit %v", circuit.Name)
@@ -151,6 +182,9 @@ func (circuit *CircuitBreaker) setOpen() {
 
 	circuit.openedOrLastTestedTime = time.Now().UnixNano()
 	circuit.open = true
+	// a ramp cut short by reopening starts over once the circuit closes again
+	atomic.StoreInt64(&circuit.closedTime, 0)
+	circuit.metrics.updateState(circuit.stateLocked())
 }
 
 func (circuit *CircuitBreaker) setClose() {
@@ -164,31 +198,87 @@ func (circuit *CircuitBreaker) setClose() {
 	log.Printf("hystrix-go: closing circuit %v", circuit.Name)
 
 	circuit.open = false
+	atomic.StoreInt64(&circuit.closedTime, time.Now().UnixNano())
+	circuit.resetSleepWindow()
 	circuit.metrics.Reset()
+	circuit.metrics.updateState(circuit.stateLocked())
+}
+
+// reportStateEvery sends the state of the circuit to the metric collectors until the circuit is flushed.
+func (circuit *CircuitBreaker) reportStateEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			circuit.mutex.RLock()
+			state := circuit.stateLocked()
+			circuit.mutex.RUnlock()
+			circuit.metrics.updateState(state)
+		case <-circuit.done:
+			return
+		}
+	}
+}
+
+// stateLocked returns the state of the circuit and its pool. The caller must hold the circuit mutex.
+func (circuit *CircuitBreaker) stateLocked() metricCollector.CircuitState {
+	return metricCollector.CircuitState{
+		Open:                  circuit.open || circuit.forceOpen,
+		ActiveCount:           circuit.executorPool.ActiveCount(),
+		WaitingCount:          circuit.executorPool.WaitingCount(),
+		MaxConcurrentRequests: circuit.executorPool.Max,
+	}
 }
 
 // ReportEvent records command metrics for tracking recent error rates and exposing data to the dashboard.
 func (circuit *CircuitBreaker) ReportEvent(eventTypes []string, start time.Time, runDuration time.Duration) error {
-	if len(eventTypes) == 0 {
+	events := make([]metricCollector.EventType, len(eventTypes))
+	for i, eventType := range eventTypes {
+		events[i] = metricCollector.EventType(eventType)
+	}
+
+	return circuit.reportExecution(&metricCollector.ExecutionResult{
+		Events:      events,
+		Start:       start,
+		RunDuration: runDuration,
+	})
+}
+
+// reportExecution records the result of an execution, completing it in place with its total duration and
+// the state of the circuit and its pool.
+func (circuit *CircuitBreaker) reportExecution(result *metricCollector.ExecutionResult) error {
+	if len(result.Events) == 0 {
 		return fmt.Errorf("no event types sent for metrics")
 	}
 
 	circuit.mutex.RLock()
 	o := circuit.open
 	circuit.mutex.RUnlock()
-	if eventTypes[0] == "success" && o {
+	if o && result.HasEvent(metricCollector.EventSuccess) {
 		circuit.setClose()
 	}
 
-	select {
-	case circuit.metrics.Updates <- &commandExecution{
-		Types:       eventTypes,
-		Start:       start,
-		RunDuration: runDuration,
-	}:
-	default:
-		return CircuitError{Message: fmt.Sprintf("metrics channel (%v) is at capacity", circuit.Name)}
-	}
+	circuit.mutex.RLock()
+	result.CircuitOpen = circuit.open || circuit.forceOpen
+	circuit.mutex.RUnlock()
+
+	result.TotalDuration = time.Since(result.Start)
+	result.ActiveCount = circuit.executorPool.ActiveCount()
+	result.WaitingCount = circuit.executorPool.WaitingCount()
+	result.MaxConcurrentRequests = circuit.executorPool.Max
+
+	circuit.metrics.update(result)
 
 	return nil
 }
+
+func hasEvent(eventTypes []metricCollector.EventType, eventType metricCollector.EventType) bool {
+	for _, e := range eventTypes {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package hystrix
 
 import (
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -98,6 +99,50 @@ func TestReportEventOpenThenClose(t *testing.T) {
 	})
 }
 
+func TestReportEventUpdatesHealthInline(t *testing.T) {
+	Convey("when a circuit opens at 50 percent of errors over 20 requests", t, func() {
+		defer Flush()
+
+		ConfigureCommand("inline_health", CommandConfig{ErrorPercentThreshold: 50, RequestVolumeThreshold: 20})
+
+		cb, _, err := GetCircuit("inline_health")
+		So(err, ShouldEqual, nil)
+
+		succeed := func() error { return nil }
+		fail := func() error { return fmt.Errorf("failure") }
+
+		Convey("and 10 commands succeed and 9 fail", func() {
+			for i := 0; i < 10; i++ {
+				So(Do("inline_health", succeed, nil), ShouldBeNil)
+			}
+			for i := 0; i < 9; i++ {
+				So(Do("inline_health", fail, nil), ShouldNotBeNil)
+			}
+
+			Convey("the circuit stays closed", func() {
+				So(cb.IsOpen(), ShouldBeFalse)
+			})
+
+			Convey("the circuit opens as soon as the next failing command returns", func() {
+				So(Do("inline_health", fail, nil), ShouldNotBeNil)
+				So(cb.IsOpen(), ShouldBeTrue)
+				So(Do("inline_health", succeed, nil), ShouldResemble, ErrCircuitOpen)
+			})
+		})
+
+		Convey("and a burst of failures larger than the metric queues is reported", func() {
+			for i := 0; i < 2*metricQueueSize; i++ {
+				So(cb.ReportEvent([]string{"failure"}, time.Now(), 0), ShouldBeNil)
+			}
+
+			Convey("every failure counts towards the health of the circuit", func() {
+				So(cb.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 2*metricQueueSize)
+				So(cb.IsOpen(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
 func TestReportEventMultiThreaded(t *testing.T) {
 	rand.Seed(time.Now().UnixNano())
 	run := func() bool {
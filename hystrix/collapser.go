@@ -0,0 +1,167 @@
+package hystrix
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// ErrNoCollapsedResult occurs when the batch function of a Collapser did not return a result for a requested key.
+var ErrNoCollapsedResult = CircuitError{Message: "no result for collapsed request"}
+
+// CollapsedResult is the outcome of a single request within a collapsed batch.
+type CollapsedResult struct {
+	Value interface{}
+	Err   error
+}
+
+// BatchFunc executes a batch of collapsed requests, returning the result for each of the given keys.
+// An error fails every request of the batch.
+type BatchFunc func(ctx context.Context, keys []string) (map[string]CollapsedResult, error)
+
+// CollapserConfig is used to tune how requests are gathered into batches.
+type CollapserConfig struct {
+	// Window is how long the first request of a batch waits for others to join it.
+	Window time.Duration
+	// MaxBatchSize executes a batch as soon as it holds this many distinct keys. 0 means no limit.
+	MaxBatchSize int
+}
+
+// Collapser gathers individual keyed requests arriving within a short window into a single batch, which is
+// executed as one hystrix command. The result for each key is fanned back out to the callers which requested it.
+// Every request collapsed into a batch is recorded as a "collapsed" event of that command.
+type Collapser struct {
+	name   string
+	config CollapserConfig
+	batch  BatchFunc
+
+	mutex   sync.Mutex
+	pending *collapsedBatch
+}
+
+type collapsedBatch struct {
+	keys     []string
+	requests int
+	waiters  map[string][]chan CollapsedResult
+	timer    *time.Timer
+}
+
+// NewCollapser creates a Collapser executing its batches as the hystrix command of the given name.
+func NewCollapser(name string, config CollapserConfig, batch BatchFunc) *Collapser {
+	return &Collapser{
+		name:   name,
+		config: config,
+		batch:  batch,
+	}
+}
+
+// Do adds the key to the pending batch and blocks until the batch has executed or the context is done.
+// The request log of the context records the request as collapsed, for as long as it waited for its batch.
+func (c *Collapser) Do(ctx context.Context, key string) (interface{}, error) {
+	start := time.Now()
+	result := make(chan CollapsedResult, 1)
+
+	c.mutex.Lock()
+	b := c.pending
+	if b == nil {
+		b = &collapsedBatch{waiters: make(map[string][]chan CollapsedResult)}
+		b.timer = time.AfterFunc(c.config.Window, func() {
+			c.flush(b)
+		})
+		c.pending = b
+	}
+
+	if _, ok := b.waiters[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+	b.waiters[key] = append(b.waiters[key], result)
+	b.requests++
+
+	full := c.config.MaxBatchSize > 0 && len(b.keys) >= c.config.MaxBatchSize
+	if full {
+		// detached right away, so that no request joins a batch which is already full
+		c.pending = nil
+	}
+	c.mutex.Unlock()
+
+	if full && b.timer.Stop() {
+		go c.execute(b)
+	}
+
+	select {
+	case r := <-result:
+		logRequest(ctx, c.name, []metricCollector.EventType{metricCollector.EventCollapsed}, time.Since(start))
+		return r.Value, r.Err
+	case <-ctx.Done():
+		eventType := metricCollector.EventContextCanceled
+		if ctx.Err() == context.DeadlineExceeded {
+			eventType = metricCollector.EventContextDeadlineExceeded
+		}
+		logRequest(ctx, c.name, []metricCollector.EventType{metricCollector.EventCollapsed, eventType}, time.Since(start))
+		return nil, ctx.Err()
+	}
+}
+
+// flush detaches the batch from the collapser once its window has passed, so that new requests start the next one,
+// and executes it.
+func (c *Collapser) flush(b *collapsedBatch) {
+	c.mutex.Lock()
+	if c.pending == b {
+		c.pending = nil
+	}
+	c.mutex.Unlock()
+
+	go c.execute(b)
+}
+
+func (c *Collapser) execute(b *collapsedBatch) {
+	// buffered so that attempts completing after the command returned never block
+	batchResults := make(chan map[string]CollapsedResult, 1)
+	ctx := withCollapsedRequests(context.Background(), b.requests)
+	err := DoC(ctx, c.name, func(ctx context.Context) error {
+		// commands executed by the batch function are not collapsed themselves
+		results, err := c.batch(withCollapsedRequests(ctx, 0), b.keys)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case batchResults <- results:
+		default:
+		}
+		return nil
+	}, nil)
+
+	var results map[string]CollapsedResult
+	if err == nil {
+		results = <-batchResults
+	}
+
+	for key, waiters := range b.waiters {
+		var r CollapsedResult
+		if err != nil {
+			r = CollapsedResult{Err: err}
+		} else if result, ok := results[key]; ok {
+			r = result
+		} else {
+			r = CollapsedResult{Err: ErrNoCollapsedResult}
+		}
+
+		for _, waiter := range waiters {
+			waiter <- r
+		}
+	}
+}
+
+type collapsedRequestsKey struct{}
+
+func withCollapsedRequests(ctx context.Context, requests int) context.Context {
+	return context.WithValue(ctx, collapsedRequestsKey{}, requests)
+}
+
+func collapsedRequests(ctx context.Context) int {
+	requests, _ := ctx.Value(collapsedRequestsKey{}).(int)
+	return requests
+}
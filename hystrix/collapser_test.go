@@ -0,0 +1,115 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type collapsedResponse struct {
+	value interface{}
+	err   error
+}
+
+func collapseConcurrently(c *Collapser, keys ...string) []collapsedResponse {
+	responses := make([]collapsedResponse, len(keys))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(keys))
+	for i, key := range keys {
+		go func(i int, key string) {
+			defer wg.Done()
+			value, err := c.Do(context.Background(), key)
+			responses[i] = collapsedResponse{value: value, err: err}
+		}(i, key)
+	}
+	wg.Wait()
+	return responses
+}
+
+func TestCollapser(t *testing.T) {
+	Convey("with a collapser whose batch function echoes its keys", t, func() {
+		defer Flush()
+
+		batches := int32(0)
+		var batchKeys []string
+		c := NewCollapser("", CollapserConfig{Window: 50 * time.Millisecond}, func(ctx context.Context, keys []string) (map[string]CollapsedResult, error) {
+			atomic.AddInt32(&batches, 1)
+			batchKeys = keys
+			results := make(map[string]CollapsedResult)
+			for _, key := range keys {
+				if key == "bad" {
+					results[key] = CollapsedResult{Err: fmt.Errorf("bad key")}
+				} else if key != "missing" {
+					results[key] = CollapsedResult{Value: "value-" + key}
+				}
+			}
+			return results, nil
+		})
+
+		Convey("concurrent requests within the window are executed as one batch", func() {
+			responses := collapseConcurrently(c, "a", "b", "a", "bad", "missing")
+
+			So(atomic.LoadInt32(&batches), ShouldEqual, 1)
+			So(len(batchKeys), ShouldEqual, 4)
+
+			Convey("and each caller receives the result for its key", func() {
+				So(responses[0].value, ShouldEqual, "value-a")
+				So(responses[1].value, ShouldEqual, "value-b")
+				So(responses[2].value, ShouldEqual, "value-a")
+				So(responses[3].err.Error(), ShouldEqual, "bad key")
+				So(responses[4].err, ShouldResemble, ErrNoCollapsedResult)
+			})
+
+			Convey("and the collapsed requests are recorded on the batch command", func() {
+				time.Sleep(10 * time.Millisecond)
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().CollapsedRequests().Sum(time.Now()), ShouldEqual, 5)
+				So(cb.metrics.DefaultCollector().Successes().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("with a collapser limited to batches of 2 keys", t, func() {
+		defer Flush()
+
+		batches := int32(0)
+		c := NewCollapser("", CollapserConfig{Window: time.Second, MaxBatchSize: 2}, func(ctx context.Context, keys []string) (map[string]CollapsedResult, error) {
+			atomic.AddInt32(&batches, 1)
+			results := make(map[string]CollapsedResult)
+			for _, key := range keys {
+				results[key] = CollapsedResult{Value: key}
+			}
+			return results, nil
+		})
+
+		Convey("a full batch is executed without waiting for the window", func() {
+			start := time.Now()
+			responses := collapseConcurrently(c, "a", "b")
+
+			So(time.Since(start), ShouldBeLessThan, 500*time.Millisecond)
+			So(atomic.LoadInt32(&batches), ShouldEqual, 1)
+			So(responses[0].value, ShouldEqual, "a")
+			So(responses[1].value, ShouldEqual, "b")
+		})
+	})
+
+	Convey("with a collapser whose batch function fails", t, func() {
+		defer Flush()
+
+		c := NewCollapser("", CollapserConfig{Window: 10 * time.Millisecond}, func(ctx context.Context, keys []string) (map[string]CollapsedResult, error) {
+			return nil, fmt.Errorf("batch_error")
+		})
+
+		Convey("every caller receives the error", func() {
+			responses := collapseConcurrently(c, "a", "b")
+
+			So(responses[0].err.Error(), ShouldEqual, "batch_error")
+			So(responses[1].err.Error(), ShouldEqual, "batch_error")
+		})
+	})
+}
@@ -20,6 +20,28 @@ type CommandBuilder struct {
 	// group a number of command (circuit name) together, useful for defining ownership/alerts/monitoring
 	// ref: https://github.com/Netflix/Hystrix/wiki/How-To-Use#command-group
 	commandGroup string
+	// how long to wait for the fallback to complete, 0 waits forever
+	fallbackTimeout int
+	retry           *hystrix.RetryPolicy
+	hedge           *hystrix.HedgePolicy
+	ramp            *hystrix.RampPolicy
+	// how the sleep window grows while probes of an open circuit keep failing
+	sleepWindowBackoff *hystrix.SleepWindowBackoff
+	// share responses of commands with the same cache key within a request cache
+	requestCacheEnabled bool
+	// record commands in the request log carried by their context
+	requestLogEnabled bool
+	// executions per second allowed across commands sharing the rate limit key, 0 disables it
+	rateLimit      float64
+	rateLimitBurst int
+	rateLimitKey   string
+	// windows over which the statistics of the circuit are kept, split into buckets
+	metricsRollingStatisticalWindow int
+	metricsRollingBuckets           int
+	metricsRollingPercentileWindow  int
+	metricsRollingPercentileBuckets int
+	// how often the state of the circuit is sent to metric collectors, 0 disables it
+	metricsStateInterval int
 }
 
 // New Create new command
@@ -33,6 +55,15 @@ func New(commandName string) *CommandBuilder {
 		sleepWindow:                 hystrix.DefaultSleepWindow,
 		errorPercentThreshold:       hystrix.DefaultErrorPercentThreshold,
 		queueSizeRejectionThreshold: nil, // will init later on build
+		fallbackTimeout:             hystrix.DefaultFallbackTimeout,
+		requestCacheEnabled:         hystrix.DefaultRequestCacheEnabled,
+		requestLogEnabled:           hystrix.DefaultRequestLogEnabled,
+
+		metricsRollingStatisticalWindow: hystrix.DefaultMetricsRollingStatisticalWindow,
+		metricsRollingBuckets:           hystrix.DefaultMetricsRollingBuckets,
+		metricsRollingPercentileWindow:  hystrix.DefaultMetricsRollingPercentileWindow,
+		metricsRollingPercentileBuckets: hystrix.DefaultMetricsRollingPercentileBuckets,
+		metricsStateInterval:            hystrix.DefaultMetricsStateInterval,
 	}
 }
 
@@ -96,6 +127,89 @@ func (cb *CommandBuilder) WithQueueSize(queueSize int) *CommandBuilder {
 	return cb
 }
 
+// WithFallbackTimeout modify fallback timeout
+func (cb *CommandBuilder) WithFallbackTimeout(fallbackTimeoutInMs int) *CommandBuilder {
+	if fallbackTimeoutInMs > 0 {
+		cb.fallbackTimeout = fallbackTimeoutInMs
+	}
+	return cb
+}
+
+// WithRetryPolicy modify retry policy, nil disables retries
+func (cb *CommandBuilder) WithRetryPolicy(retry *hystrix.RetryPolicy) *CommandBuilder {
+	cb.retry = retry
+	return cb
+}
+
+// WithHedgePolicy modify hedge policy, nil disables hedging
+func (cb *CommandBuilder) WithHedgePolicy(hedge *hystrix.HedgePolicy) *CommandBuilder {
+	cb.hedge = hedge
+	return cb
+}
+
+// WithSleepWindowBackoff modify how the sleep window grows after failed probes, nil keeps it fixed
+func (cb *CommandBuilder) WithSleepWindowBackoff(sleepWindowBackoff *hystrix.SleepWindowBackoff) *CommandBuilder {
+	cb.sleepWindowBackoff = sleepWindowBackoff
+	return cb
+}
+
+// WithRampPolicy modify how traffic ramps up after the circuit closes, nil admits all traffic at once
+func (cb *CommandBuilder) WithRampPolicy(ramp *hystrix.RampPolicy) *CommandBuilder {
+	cb.ramp = ramp
+	return cb
+}
+
+// WithRequestCacheEnabled modify whether the request cache is used
+func (cb *CommandBuilder) WithRequestCacheEnabled(requestCacheEnabled bool) *CommandBuilder {
+	cb.requestCacheEnabled = requestCacheEnabled
+	return cb
+}
+
+// WithRequestLogEnabled modify whether commands are recorded in the request log
+func (cb *CommandBuilder) WithRequestLogEnabled(requestLogEnabled bool) *CommandBuilder {
+	cb.requestLogEnabled = requestLogEnabled
+	return cb
+}
+
+// WithRateLimit modify the executions per second allowed and how many may happen at once, 0 disables rate limiting
+func (cb *CommandBuilder) WithRateLimit(perSecond float64, burst int) *CommandBuilder {
+	cb.rateLimit = perSecond
+	cb.rateLimitBurst = burst
+	return cb
+}
+
+// WithRateLimitKey modify the key under which the rate limit is shared with other commands
+func (cb *CommandBuilder) WithRateLimitKey(rateLimitKey string) *CommandBuilder {
+	cb.rateLimitKey = rateLimitKey
+	return cb
+}
+
+// WithMetricsRollingStatisticalWindow modify the window over which counters are kept and the number of buckets it is split into
+func (cb *CommandBuilder) WithMetricsRollingStatisticalWindow(windowInMs int, buckets int) *CommandBuilder {
+	if windowInMs > 0 && buckets > 0 {
+		cb.metricsRollingStatisticalWindow = windowInMs
+		cb.metricsRollingBuckets = buckets
+	}
+	return cb
+}
+
+// WithMetricsRollingPercentileWindow modify the window over which durations are kept and the number of buckets it is split into
+func (cb *CommandBuilder) WithMetricsRollingPercentileWindow(windowInMs int, buckets int) *CommandBuilder {
+	if windowInMs > 0 && buckets > 0 {
+		cb.metricsRollingPercentileWindow = windowInMs
+		cb.metricsRollingPercentileBuckets = buckets
+	}
+	return cb
+}
+
+// WithMetricsStateInterval modify how often the state of the circuit and its pool is sent to metric collectors, 0 only sending it when the circuit opens or closes
+func (cb *CommandBuilder) WithMetricsStateInterval(intervalInMs int) *CommandBuilder {
+	if intervalInMs >= 0 {
+		cb.metricsStateInterval = intervalInMs
+	}
+	return cb
+}
+
 // Build the command setting, Use hystrix.Initialize for setup
 func (cb *CommandBuilder) Build() *hystrix.Settings {
 
@@ -114,5 +228,21 @@ func (cb *CommandBuilder) Build() *hystrix.Settings {
 		RequestVolumeThreshold:      uint64(cb.requestVolumeThreshold),
 		SleepWindow:                 time.Duration(cb.sleepWindow) * time.Millisecond,
 		QueueSizeRejectionThreshold: *cb.queueSizeRejectionThreshold,
+		FallbackTimeout:             time.Duration(cb.fallbackTimeout) * time.Millisecond,
+		Retry:                       cb.retry,
+		Hedge:                       cb.hedge,
+		RequestCacheEnabled:         cb.requestCacheEnabled,
+		RequestLogEnabled:           cb.requestLogEnabled,
+		Ramp:                        cb.ramp,
+		SleepWindowBackoff:          cb.sleepWindowBackoff,
+		RateLimit:                   cb.rateLimit,
+		RateLimitBurst:              cb.rateLimitBurst,
+		RateLimitKey:                cb.rateLimitKey,
+
+		MetricsRollingStatisticalWindow: time.Duration(cb.metricsRollingStatisticalWindow) * time.Millisecond,
+		MetricsRollingBuckets:           cb.metricsRollingBuckets,
+		MetricsRollingPercentileWindow:  time.Duration(cb.metricsRollingPercentileWindow) * time.Millisecond,
+		MetricsRollingPercentileBuckets: cb.metricsRollingPercentileBuckets,
+		MetricsStateInterval:            time.Duration(cb.metricsStateInterval) * time.Millisecond,
 	}
 }
@@ -71,6 +71,73 @@ func TestCommandBuilderNoQueue(t *testing.T) {
 	})
 }
 
+func TestCommandBuilderWithFallbackTimeout(t *testing.T) {
+	Convey("given a command configured with a fallback timeout", t, func() {
+		commandSetting := New("command4").WithFallbackTimeout(250).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the fallback timeout should be the same", func() {
+			circuits := hystrix.GetCircuitSettings()
+			So(circuits["command4"].FallbackTimeout.Nanoseconds()/1000000, ShouldEqual, 250)
+			So(circuits["command4"].Timeout.Nanoseconds()/1000000, ShouldEqual, hystrix.DefaultTimeout)
+		})
+	})
+}
+
+func TestCommandBuilderWithMetricsRollingWindows(t *testing.T) {
+	Convey("given a command configured with rolling windows", t, func() {
+		commandSetting := New("command1").WithMetricsRollingStatisticalWindow(30000, 30).WithMetricsRollingPercentileWindow(0, 6).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the rolling windows should be the same, ignoring invalid ones", func() {
+			circuits := hystrix.GetCircuitSettings()
+			So(circuits["command1"].MetricsRollingStatisticalWindow, ShouldEqual, 30*time.Second)
+			So(circuits["command1"].MetricsRollingBuckets, ShouldEqual, 30)
+			So(circuits["command1"].MetricsRollingPercentileWindow.Nanoseconds()/1000000, ShouldEqual, hystrix.DefaultMetricsRollingPercentileWindow)
+			So(circuits["command1"].MetricsRollingPercentileBuckets, ShouldEqual, hystrix.DefaultMetricsRollingPercentileBuckets)
+		})
+	})
+}
+
+func TestCommandBuilderWithMetricsStateInterval(t *testing.T) {
+	Convey("given a command configured without periodic state updates", t, func() {
+		commandSetting := New("command1").WithMetricsStateInterval(0).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the interval should be the same", func() {
+			circuits := hystrix.GetCircuitSettings()
+			So(circuits["command1"].MetricsStateInterval, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestCommandBuilderWithRampPolicy(t *testing.T) {
+	Convey("given a command configured with a ramp policy", t, func() {
+		commandSetting := New("command1").WithRampPolicy(&hystrix.RampPolicy{Duration: time.Minute, Exponential: true}).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the ramp policy should be the same", func() {
+			circuits := hystrix.GetCircuitSettings()
+			So(circuits["command1"].Ramp.Duration, ShouldEqual, time.Minute)
+			So(circuits["command1"].Ramp.Exponential, ShouldBeTrue)
+		})
+	})
+}
+
+func TestCommandBuilderWithRateLimit(t *testing.T) {
+	Convey("given a command configured with a shared rate limit", t, func() {
+		commandSetting := New("command1").WithRateLimit(100, 10).WithRateLimitKey("shared").Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the rate limit should be the same", func() {
+			circuits := hystrix.GetCircuitSettings()
+			So(circuits["command1"].RateLimit, ShouldEqual, 100)
+			So(circuits["command1"].RateLimitBurst, ShouldEqual, 10)
+			So(circuits["command1"].RateLimitKey, ShouldEqual, "shared")
+		})
+	})
+}
+
 func TestOverflowWithoutQueue(t *testing.T) {
 	defer hystrix.Flush()
 
@@ -6,8 +6,6 @@ import (
 	"net/http"
 	"sync"
 	"time"
-
-	"github.com/myteksi/hystrix-go/hystrix/rolling"
 )
 
 const (
@@ -75,62 +73,58 @@ func (sh *StreamHandler) loop() {
 	for {
 		select {
 		case <-tick:
-			circuitBreakersMutex.RLock()
-			for _, cb := range circuitBreakers {
-				_ = sh.publishMetrics(cb)
-				_ = sh.publishThreadPools(cb.executorPool)
+			for _, snapshot := range AllMetrics() {
+				_ = sh.publishMetrics(snapshot)
+				_ = sh.publishThreadPools(snapshot)
 			}
-			circuitBreakersMutex.RUnlock()
 		case <-sh.done:
 			return
 		}
 	}
 }
 
-func (sh *StreamHandler) publishMetrics(cb *CircuitBreaker) error {
-	now := time.Now()
-	reqCount := cb.metrics.Requests().Sum(now)
-	errCount := cb.metrics.DefaultCollector().Errors().Sum(now)
-	errPct := cb.metrics.ErrorPercent(now)
-
+func (sh *StreamHandler) publishMetrics(snapshot Snapshot) error {
 	eventBytes, err := json.Marshal(&streamCmdMetric{
 		Type:               "HystrixCommand",
-		Name:               cb.Name,
-		Group:              cb.Name,
-		Time:               currentTime(),
+		Name:               snapshot.Name,
+		Group:              snapshot.Name,
+		Time:               snapshot.Time.UnixNano() / int64(time.Millisecond),
 		ReportingHosts:     1,
-		LatencyTotal:       generateLatencyTimings(cb.metrics.DefaultCollector().TotalDuration()),
-		LatencyTotalMean:   cb.metrics.DefaultCollector().TotalDuration().Mean(),
-		LatencyExecute:     generateLatencyTimings(cb.metrics.DefaultCollector().RunDuration()),
-		LatencyExecuteMean: cb.metrics.DefaultCollector().RunDuration().Mean(),
+		LatencyTotal:       generateLatencyTimings(snapshot.TotalLatency),
+		LatencyTotalMean:   milliseconds(snapshot.TotalLatency.Mean),
+		LatencyExecute:     generateLatencyTimings(snapshot.RunLatency),
+		LatencyExecuteMean: milliseconds(snapshot.RunLatency.Mean),
 
 		streamCmdHealthMetric: streamCmdHealthMetric{
-			RequestCount:       uint32(reqCount),
-			ErrorCount:         uint32(errCount),
-			ErrorPct:           uint32(errPct),
-			CircuitBreakerOpen: cb.IsOpen(),
+			RequestCount:       uint32(snapshot.Requests),
+			ErrorCount:         uint32(snapshot.Errors),
+			ErrorPct:           uint32(snapshot.ErrorPercent),
+			CircuitBreakerOpen: snapshot.Open,
 		},
 
 		streamCmdRollingCountMetric: streamCmdRollingCountMetric{
-
-			RollingCountSuccess:            uint32(cb.metrics.DefaultCollector().Successes().Sum(now)),
-			RollingCountFailure:            uint32(cb.metrics.DefaultCollector().Failures().Sum(now)),
-			RollingCountThreadPoolRejected: uint32(cb.metrics.DefaultCollector().Rejects().Sum(now)),
-			RollingCountShortCircuited:     uint32(cb.metrics.DefaultCollector().ShortCircuits().Sum(now)),
-			RollingCountTimeout:            uint32(cb.metrics.DefaultCollector().Timeouts().Sum(now)),
-			RollingCountFallbackSuccess:    uint32(cb.metrics.DefaultCollector().FallbackSuccesses().Sum(now)),
-			RollingCountFallbackFailure:    uint32(cb.metrics.DefaultCollector().FallbackFailures().Sum(now)),
+			RollingCountCollapsedRequests:  uint32(snapshot.Counts.CollapsedRequests),
+			RollingCountSuccess:            uint32(snapshot.Counts.Successes),
+			RollingCountFailure:            uint32(snapshot.Counts.Failures),
+			RollingCountThreadPoolRejected: uint32(snapshot.Counts.Rejects),
+			RollingCountShortCircuited:     uint32(snapshot.Counts.ShortCircuits),
+			RollingCountTimeout:            uint32(snapshot.Counts.Timeouts),
+			RollingCountFallbackSuccess:    uint32(snapshot.Counts.FallbackSuccesses),
+			RollingCountFallbackFailure:    uint32(snapshot.Counts.FallbackFailures),
+			RollingCountResponsesFromCache: uint32(snapshot.Counts.ResponsesFromCache),
 		},
 		steamCmdPropertiesMetric: steamCmdPropertiesMetric{
 			// TODO: all hard-coded values should become configurable settings, per circuit
-			RollingStatsWindow:                   10000,
+			RollingStatsWindow:                   milliseconds(rollingWindows(snapshot.Name).StatisticalWindow),
 			ExecutionIsolationStrategy:           "THREAD",
 			CircuitBreakerEnabled:                true,
 			CircuitBreakerForceClosed:            false,
-			CircuitBreakerForceOpen:              cb.forceOpen,
-			CircuitBreakerErrorThresholdPercent:  uint32(getSettings(cb.Name).ErrorPercentThreshold),
-			CircuitBreakerSleepWindow:            uint32(getSettings(cb.Name).SleepWindow.Seconds() * 1000),
-			CircuitBreakerRequestVolumeThreshold: uint32(getSettings(cb.Name).RequestVolumeThreshold),
+			CircuitBreakerForceOpen:              snapshot.ForceOpen,
+			CircuitBreakerErrorThresholdPercent:  uint32(snapshot.Settings.ErrorPercentThreshold),
+			CircuitBreakerSleepWindow:            milliseconds(snapshot.EffectiveSleepWindow),
+			CircuitBreakerRequestVolumeThreshold: uint32(snapshot.Settings.RequestVolumeThreshold),
+			RequestCacheEnabled:                  snapshot.Settings.RequestCacheEnabled,
+			RequestLogEnabled:                    snapshot.Settings.RequestLogEnabled,
 		},
 	})
 	if err != nil {
@@ -144,29 +138,29 @@ func (sh *StreamHandler) publishMetrics(cb *CircuitBreaker) error {
 	return nil
 }
 
-func (sh *StreamHandler) publishThreadPools(pool *bufferedExecutorPool) error {
-	now := time.Now()
+func (sh *StreamHandler) publishThreadPools(snapshot Snapshot) error {
+	pool := snapshot.Pool
 
 	eventBytes, err := json.Marshal(&streamThreadPoolMetric{
 		Type:           "HystrixThreadPool",
 		Name:           pool.Name,
 		ReportingHosts: 1,
 
-		CurrentActiveCount:        uint32(pool.ActiveCount()),
+		CurrentActiveCount:        uint32(pool.Active),
 		CurrentTaskCount:          0,
 		CurrentCompletedTaskCount: 0,
 
-		RollingCountThreadsExecuted: uint32(pool.Metrics.Executed.Sum(now)),
-		RollingMaxActiveThreads:     uint32(pool.Metrics.MaxActiveRequests.Max(now)),
+		RollingCountThreadsExecuted: uint32(pool.Executed),
+		RollingMaxActiveThreads:     uint32(pool.MaxActive),
 
-		CurrentPoolSize:        uint32(pool.Max),
-		CurrentCorePoolSize:    uint32(pool.Max),
-		CurrentLargestPoolSize: uint32(pool.Max),
-		CurrentMaximumPoolSize: uint32(pool.Max),
+		CurrentPoolSize:        uint32(pool.MaxConcurrentRequests),
+		CurrentCorePoolSize:    uint32(pool.MaxConcurrentRequests),
+		CurrentLargestPoolSize: uint32(pool.MaxConcurrentRequests),
+		CurrentMaximumPoolSize: uint32(pool.MaxConcurrentRequests),
 
-		RollingStatsWindow:          10000,
+		RollingStatsWindow:          milliseconds(rollingWindows(snapshot.Name).StatisticalWindow),
 		QueueSizeRejectionThreshold: uint32(pool.QueueSizeRejectionThreshold),
-		CurrentQueueSize:            uint32(pool.WaitingCount()),
+		CurrentQueueSize:            uint32(pool.Waiting),
 	})
 	if err != nil {
 		return err
@@ -226,20 +220,24 @@ func (sh *StreamHandler) unregister(req *http.Request) {
 	sh.mu.Unlock()
 }
 
-func generateLatencyTimings(r *rolling.Timing) streamCmdLatency {
+func generateLatencyTimings(l LatencySnapshot) streamCmdLatency {
 	return streamCmdLatency{
-		Timing0:   r.Percentile(0),
-		Timing25:  r.Percentile(25),
-		Timing50:  r.Percentile(50),
-		Timing75:  r.Percentile(75),
-		Timing90:  r.Percentile(90),
-		Timing95:  r.Percentile(95),
-		Timing99:  r.Percentile(99),
-		Timing995: r.Percentile(99.5),
-		Timing100: r.Percentile(100),
+		Timing0:   milliseconds(l.P0),
+		Timing25:  milliseconds(l.P25),
+		Timing50:  milliseconds(l.P50),
+		Timing75:  milliseconds(l.P75),
+		Timing90:  milliseconds(l.P90),
+		Timing95:  milliseconds(l.P95),
+		Timing99:  milliseconds(l.P99),
+		Timing995: milliseconds(l.P995),
+		Timing100: milliseconds(l.P100),
 	}
 }
 
+func milliseconds(d time.Duration) uint32 {
+	return uint32(d / time.Millisecond)
+}
+
 type streamCmdMetric struct {
 	streamCmdHealthMetric
 	streamCmdRollingCountMetric
@@ -330,7 +328,3 @@ type streamThreadPoolMetric struct {
 	RollingStatsWindow          uint32 `json:"propertyValue_metricsRollingStatisticalWindowInMilliseconds"`
 	QueueSizeRejectionThreshold uint32 `json:"propertyValue_queueSizeRejectionThreshold"`
 }
-
-func currentTime() int64 {
-	return time.Now().UnixNano() / int64(1000000)
-}
@@ -0,0 +1,72 @@
+package hystrix
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// FallbackStep is a single alternative in a fallback chain. Each step is executed as the hystrix
+// command of the same name, so it is guarded by its own circuit, settings and metrics.
+type FallbackStep struct {
+	Name     string
+	Fallback fallbackFunc
+}
+
+// A FallbackChainError is returned by DoChain when the run function and every step of the
+// fallback chain failed. Steps and StepErrors list the attempted steps in order.
+type FallbackChainError struct {
+	RunError   error
+	Steps      []string
+	StepErrors []error
+}
+
+func (e FallbackChainError) Error() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "fallback chain exhausted. run error was '%v'", e.RunError)
+	for i, step := range e.Steps {
+		fmt.Fprintf(&b, ", %v failed with '%v'", step, e.StepErrors[i])
+	}
+	return b.String()
+}
+
+// LastStep returns the name of the last step attempted before the chain gave up.
+func (e FallbackChainError) LastStep() string {
+	if len(e.Steps) == 0 {
+		return ""
+	}
+	return e.Steps[len(e.Steps)-1]
+}
+
+// DoChain runs your function in a synchronous manner like Do, but instead of a single fallback it
+// tries each step in order until one succeeds. Every step receives the error of the previous attempt.
+// The steps run once the command returned, so the command records its own failure, without a fallback,
+// and each step is only bound by the settings of its own command.
+//
+// It returns the name of the command or step which served the response. When everything failed the
+// error is a FallbackChainError describing each attempt.
+func DoChain(name string, run runFunc, steps ...FallbackStep) (string, error) {
+	err := Do(name, run, nil)
+	if err == nil || len(steps) == 0 {
+		return name, err
+	}
+
+	chainErr := FallbackChainError{RunError: err}
+	for _, step := range steps {
+		stepErr := runFallbackStep(step, err)
+		if stepErr == nil {
+			return step.Name, nil
+		}
+
+		chainErr.Steps = append(chainErr.Steps, step.Name)
+		chainErr.StepErrors = append(chainErr.StepErrors, stepErr)
+		err = stepErr
+	}
+
+	return "", chainErr
+}
+
+func runFallbackStep(step FallbackStep, err error) error {
+	return Do(step.Name, func() error {
+		return step.Fallback(err)
+	}, nil)
+}
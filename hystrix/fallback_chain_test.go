@@ -0,0 +1,95 @@
+package hystrix
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDoChain(t *testing.T) {
+	Convey("with a command which succeeds", t, func() {
+		defer Flush()
+
+		servedBy, err := DoChain("primary", func() error {
+			return nil
+		}, FallbackStep{Name: "replica", Fallback: func(err error) error {
+			return nil
+		}})
+
+		Convey("the command itself serves the response", func() {
+			So(err, ShouldBeNil)
+			So(servedBy, ShouldEqual, "primary")
+		})
+	})
+
+	Convey("with a command which fails and a chain of fallbacks", t, func() {
+		defer Flush()
+
+		var staleCacheErr error
+		servedBy, err := DoChain("primary", func() error {
+			return fmt.Errorf("primary_error")
+		}, FallbackStep{Name: "replica", Fallback: func(err error) error {
+			return fmt.Errorf("replica_error")
+		}}, FallbackStep{Name: "stale-cache", Fallback: func(err error) error {
+			staleCacheErr = err
+			return nil
+		}}, FallbackStep{Name: "static-default", Fallback: func(err error) error {
+			return nil
+		}})
+
+		Convey("the first succeeding step serves the response", func() {
+			So(err, ShouldBeNil)
+			So(servedBy, ShouldEqual, "stale-cache")
+			So(staleCacheErr.Error(), ShouldEqual, "replica_error")
+		})
+
+		Convey("each step is tracked by its own circuit", func() {
+			primary, _, _ := GetCircuit("primary")
+			replica, _, _ := GetCircuit("replica")
+			staleCache, _, _ := GetCircuit("stale-cache")
+			So(primary.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 1)
+			So(primary.metrics.DefaultCollector().FallbackSuccesses().Sum(time.Now()), ShouldEqual, 0)
+			So(replica.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 1)
+			So(staleCache.metrics.DefaultCollector().Successes().Sum(time.Now()), ShouldEqual, 1)
+		})
+	})
+
+	Convey("with a command which fails and a fallback slower than its fallback timeout", t, func() {
+		defer Flush()
+		ConfigureCommand("primary", CommandConfig{FallbackTimeout: 10})
+
+		servedBy, err := DoChain("primary", func() error {
+			return fmt.Errorf("primary_error")
+		}, FallbackStep{Name: "replica", Fallback: func(err error) error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}})
+
+		Convey("the step is not bound by the settings of the command", func() {
+			So(err, ShouldBeNil)
+			So(servedBy, ShouldEqual, "replica")
+		})
+	})
+
+	Convey("with a command whose every fallback fails", t, func() {
+		defer Flush()
+
+		servedBy, err := DoChain("primary", func() error {
+			return fmt.Errorf("primary_error")
+		}, FallbackStep{Name: "replica", Fallback: func(err error) error {
+			return fmt.Errorf("replica_error")
+		}}, FallbackStep{Name: "static-default", Fallback: func(err error) error {
+			return fmt.Errorf("default_error")
+		}})
+
+		Convey("a chain error records every attempt", func() {
+			So(servedBy, ShouldEqual, "")
+			chainErr, ok := err.(FallbackChainError)
+			So(ok, ShouldBeTrue)
+			So(chainErr.LastStep(), ShouldEqual, "static-default")
+			So(chainErr.Error(), ShouldEqual, "fallback chain exhausted. run error was 'primary_error', replica failed with 'replica_error', static-default failed with 'default_error'")
+		})
+	})
+}
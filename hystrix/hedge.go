@@ -0,0 +1,97 @@
+package hystrix
+
+import (
+	"context"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// HedgePolicy describes how slow attempts of an idempotent run function are hedged. When an attempt has not
+// returned within Delay, another one is started alongside it, as long as the circuit has a spare execution
+// ticket. The first success is returned and the context of the remaining attempts is canceled.
+type HedgePolicy struct {
+	// MaxAttempts is the total number of concurrent attempts, including the first one.
+	MaxAttempts int
+	// Delay is how long to wait before starting each additional attempt. If 0, the rolling 95th percentile
+	// of the run duration is used, and no attempt is hedged until some durations have been recorded.
+	Delay time.Duration
+}
+
+type hedgeResult struct {
+	err    error
+	hedged bool
+}
+
+// runAttempt calls the run function once, hedging it according to the HedgePolicy of the circuit.
+// Every additional attempt is reported as a "hedge" event, and a success from one of them as a "hedge-win" event.
+func (c *command) runAttempt(ctx context.Context) error {
+	policy := getSettings(c.circuit.Name).Hedge
+	if policy == nil || policy.MaxAttempts < 2 {
+		return c.run(ctx)
+	}
+
+	delay := policy.Delay
+	if delay <= 0 {
+		delay = c.circuit.metrics.DefaultCollector().RunDuration().PercentileDuration(95)
+	}
+	if delay <= 0 {
+		return c.run(ctx)
+	}
+
+	hedgeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, policy.MaxAttempts)
+	launch := func(hedged bool, ticket *struct{}) {
+		go func() {
+			err := c.run(hedgeCtx)
+			if ticket != nil {
+				c.circuit.executorPool.Return(ticket)
+			}
+			results <- hedgeResult{err: err, hedged: hedged}
+		}()
+	}
+
+	launch(false, nil)
+	launched, inFlight := 1, 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var err error
+	for inFlight > 0 {
+		select {
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				if r.hedged {
+					c.reportEvent(metricCollector.EventHedgeWin)
+				}
+				return nil
+			}
+			err = r.err
+		case <-timer.C:
+			select {
+			case ticket := <-c.circuit.executorPool.Tickets:
+				c.reportEvent(metricCollector.EventHedge)
+				launch(true, ticket)
+				launched++
+				inFlight++
+			default:
+				// hedging never queues, it only uses spare capacity
+			}
+			if launched < policy.MaxAttempts {
+				timer.Reset(delay)
+			}
+		case <-c.timeoutChan:
+			// timed out or canceled, the result is discarded anyway
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return ErrTimeout
+		}
+	}
+
+	return err
+}
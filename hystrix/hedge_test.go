@@ -0,0 +1,85 @@
+package hystrix
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func slowFirstAttempt(attempts *int32, canceled chan struct{}) runFuncC {
+	return func(ctx context.Context) error {
+		if atomic.AddInt32(attempts, 1) > 1 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			close(canceled)
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+			return nil
+		}
+	}
+}
+
+func TestHedge(t *testing.T) {
+	Convey("with a hedged command whose first attempt is slow", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{HedgeMaxAttempts: 2, HedgeDelay: 20})
+
+		attempts := int32(0)
+		canceled := make(chan struct{})
+		err := DoC(context.Background(), "", slowFirstAttempt(&attempts, canceled), nil)
+
+		Convey("the hedged attempt serves the response", func() {
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 2)
+
+			Convey("the slow attempt is canceled", func() {
+				<-canceled
+			})
+
+			Convey("hedges and wins are recorded", func() {
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().Hedges().Sum(time.Now()), ShouldEqual, 1)
+				So(cb.metrics.DefaultCollector().HedgeWins().Sum(time.Now()), ShouldEqual, 1)
+				So(cb.metrics.DefaultCollector().Successes().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("with a hedged command which has no spare execution ticket", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{MaxConcurrentRequests: 1, HedgeMaxAttempts: 2, HedgeDelay: 20})
+
+		attempts := int32(0)
+		err := DoC(context.Background(), "", slowFirstAttempt(&attempts, make(chan struct{})), nil)
+
+		Convey("no attempt is hedged", func() {
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+		})
+	})
+
+	Convey("with a hedged command whose caller gives up", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{HedgeMaxAttempts: 2, HedgeDelay: 1000})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		errChan := GoC(ctx, "", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}, nil)
+		<-started
+		cancel()
+
+		Convey("the context error is returned", func() {
+			So(<-errChan == context.Canceled, ShouldBeTrue)
+		})
+	})
+}
@@ -1,14 +1,19 @@
 package hystrix
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
 	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
 )
 
 type runFunc func() error
 type fallbackFunc func(error) error
+type runFuncC func(context.Context) error
+type fallbackFuncC func(context.Context, error) error
 
 // A CircuitError is an error which models various failure states of execution,
 // such as the circuit being open or a timeout.
@@ -25,20 +30,30 @@ func (e CircuitError) Error() string {
 type command struct {
 	mu sync.RWMutex
 
+	ctx            context.Context
 	ticket         *struct{}
 	overflowTicket *struct{}
 	start          time.Time
 	errChan        chan error
 	finished       chan bool
-	timeoutChan    chan struct{}
-	fallbackOnce   *sync.Once
-	circuit        *CircuitBreaker
-	run            runFunc
-	fallback       fallbackFunc
-	runDuration    time.Duration
-	events         []string
-	timedOut       bool
-	ticketChecked  chan struct{}
+	// completed is closed once the execution has been recorded and its error, if any, sent on errChan
+	completed chan struct{}
+	// result is the error returned to the caller, once the fallback was tried
+	result        error
+	timeoutChan   chan struct{}
+	fallbackOnce  *sync.Once
+	circuit       *CircuitBreaker
+	run           runFuncC
+	fallback      fallbackFuncC
+	runDuration   time.Duration
+	queueDuration time.Duration
+	events        []metricCollector.EventType
+	err           error
+	timedOut      bool
+	probe         bool
+	// ran is set once the run function was started, after the command got a ticket
+	ran           bool
+	ticketChecked chan struct{}
 }
 
 var (
@@ -48,6 +63,8 @@ var (
 	ErrCircuitOpen = CircuitError{Message: "circuit open"}
 	// ErrTimeout occurs when the provided function takes too long to execute.
 	ErrTimeout = CircuitError{Message: "timeout"}
+	// ErrFallbackTimeout occurs when the fallback function takes longer than the configured FallbackTimeout.
+	ErrFallbackTimeout = CircuitError{Message: "fallback timeout"}
 )
 
 // Go runs your function while tracking the health of previous calls to it.
@@ -56,16 +73,46 @@ var (
 //
 // Define a fallback function if you want to define some code to execute during outages.
 func Go(name string, run runFunc, fallback fallbackFunc) chan error {
+	runC := func(ctx context.Context) error {
+		return run()
+	}
+	var fallbackC fallbackFuncC
+	if fallback != nil {
+		fallbackC = func(ctx context.Context, err error) error {
+			return fallback(err)
+		}
+	}
+	return GoC(context.Background(), name, runC, fallbackC)
+}
+
+// GoC runs your function while tracking the health of previous calls to it, like Go.
+// The context is passed to your run and fallback functions, and once it is done the
+// command stops waiting for run and returns the context error through the fallback.
+//
+// Errors are sent on the returned channel once the execution has been recorded in the
+// health of the circuit and the request log.
+func GoC(ctx context.Context, name string, run runFuncC, fallback fallbackFuncC) chan error {
+	return goC(ctx, name, run, fallback).errChan
+}
+
+func goC(ctx context.Context, name string, run runFuncC, fallback fallbackFuncC) *command {
 	cmd := &command{
 		run:           run,
 		fallback:      fallback,
 		start:         time.Now(),
 		errChan:       make(chan error, 1),
 		finished:      make(chan bool, 1),
+		completed:     make(chan struct{}),
 		fallbackOnce:  &sync.Once{},
 		timeoutChan:   make(chan struct{}, 1),
 		ticketChecked: make(chan struct{}),
 	}
+	// the execution is traced from the start, so that the spans of the run and fallback functions are its children
+	ctx, endExecution := startExecution(ctx, name)
+	// run and fallback functions receive the command through their context, so that helpers
+	// executing inside of them can report events of their own
+	ctx = context.WithValue(ctx, commandKey{}, cmd)
+	cmd.ctx = ctx
 
 	// dont have methods with explicit params and returns
 	// let data come in and out naturally, like with any closure
@@ -73,11 +120,22 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 
 	circuit, _, err := GetCircuit(name)
 	if err != nil {
+		endExecution(metricCollector.ExecutionResult{
+			Start:         cmd.start,
+			TotalDuration: time.Since(cmd.start),
+			Error:         err,
+			Context:       ctx,
+		})
 		cmd.errChan <- err
-		return cmd.errChan
+		close(cmd.completed)
+		return cmd
 	}
 	cmd.circuit = circuit
 
+	for i := 0; i < collapsedRequests(ctx); i++ {
+		cmd.reportEvent(metricCollector.EventCollapsed)
+	}
+
 	go func() {
 		defer func() {
 			cmd.finished <- true
@@ -86,12 +144,32 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 		// Circuits get opened when recent executions have shown to have a high error rate.
 		// Rejecting new executions allows backends to recover, and the circuit will allow
 		// new traffic when it feels a healthly state has returned.
-		if !cmd.circuit.AllowRequest() {
+		allowed, probe := cmd.circuit.allowRequest()
+		cmd.mu.Lock()
+		cmd.probe = probe
+		cmd.mu.Unlock()
+		if !allowed {
 			cmd.errorWithFallback(ErrCircuitOpen)
 			close(cmd.ticketChecked)
 			return
 		}
 
+		// A circuit which just closed admits its traffic gradually, so that the recovered backend is not
+		// knocked over again right away. The probe of an open circuit is never turned away, or it could not close.
+		if !probe && !cmd.circuit.allowRamp() {
+			cmd.errorWithFallback(ErrCircuitRamping)
+			close(cmd.ticketChecked)
+			return
+		}
+
+		// Downstreams with a hard quota are protected by rejecting executions above the rate limit,
+		// before they hold on to a ticket.
+		if !allowRate(name) {
+			cmd.errorWithFallback(ErrRateLimited)
+			close(cmd.ticketChecked)
+			return
+		}
+
 		// As backends falter, requests take longer but don't always fail.
 		//
 		// When requests slow down but the incoming rate of requests stays the same, you have to
@@ -105,7 +183,7 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 		default:
 			select {
 			case t := <-circuit.executorPool.WaitingTicket:
-				cmd.reportEvent("queued")
+				cmd.reportEvent(metricCollector.EventQueued)
 				cmd.setOverflowTicket(t)
 			default: // Unable to get execution or waiting ticket, error with MaxConcurrency
 				cmd.errorWithFallback(ErrMaxConcurrency)
@@ -119,6 +197,7 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 				// return the ticket right away as it is not required
 				cmd.circuit.executorPool.ReturnWaitingTicket(cmd.overflowTicket)
 				cmd.setTicket(executionTicket)
+				cmd.setQueueDuration(time.Since(cmd.start))
 				if circuit.IsOpen() {
 					cmd.errorWithFallback(ErrCircuitOpen)
 					close(cmd.ticketChecked)
@@ -133,8 +212,11 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 		}
 
 		close(cmd.ticketChecked)
+		cmd.mu.Lock()
+		cmd.ran = true
+		cmd.mu.Unlock()
 		runStart := time.Now()
-		runErr := run()
+		runErr := cmd.runWithRetry(ctx)
 
 		if cmd.isTimedOut() {
 			return
@@ -147,7 +229,7 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 			return
 		}
 
-		cmd.reportEvent("success")
+		cmd.reportEvent(metricCollector.EventSuccess)
 	}()
 
 	go func() {
@@ -156,13 +238,44 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 
 			cmd.mu.Lock()
 			cmd.circuit.executorPool.Return(cmd.ticket)
-			copyEvents := append([]string(nil), cmd.events...)
+			copyEvents := append([]metricCollector.EventType(nil), cmd.events...)
+			cmdErr := cmd.err
+			queueDuration := cmd.queueDuration
+			probe := cmd.probe && cmd.ran
 			cmd.mu.Unlock()
 
-			err := cmd.circuit.ReportEvent(copyEvents, cmd.start, cmd.getRunDuration())
+			// only a probe which reached the backend tells whether it recovered, one turned away on the way or
+			// given up by its caller says nothing about it
+			if probe && !hasEvent(copyEvents, metricCollector.EventSuccess) &&
+				!hasEvent(copyEvents, metricCollector.EventContextCanceled) &&
+				!hasEvent(copyEvents, metricCollector.EventContextDeadlineExceeded) {
+				cmd.circuit.probeFailed()
+			}
+
+			execution := &metricCollector.ExecutionResult{
+				Events:        copyEvents,
+				Start:         cmd.start,
+				RunDuration:   cmd.getRunDuration(),
+				QueueDuration: queueDuration,
+				Error:         cmdErr,
+				Labels:        metricLabels(ctx),
+				Context:       ctx,
+			}
+			err := cmd.circuit.reportExecution(execution)
 			if err != nil {
 				log.Print(err)
 			}
+			logRequest(ctx, name, copyEvents, cmd.getRunDuration())
+			endExecution(*execution)
+
+			// the caller only hears about the execution once it has been recorded
+			cmd.mu.RLock()
+			result := cmd.result
+			cmd.mu.RUnlock()
+			if result != nil {
+				cmd.errChan <- result
+			}
+			close(cmd.completed)
 		}()
 
 		timer := time.NewTimer(getSettings(name).Timeout)
@@ -170,6 +283,13 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 
 		select {
 		case <-cmd.finished:
+		case <-ctx.Done():
+			close(cmd.timeoutChan)
+			// the caller is no longer waiting, so the result of run is discarded like after a timeout
+			cmd.mu.Lock()
+			cmd.timedOut = true
+			cmd.mu.Unlock()
+			cmd.errorWithFallback(ctx.Err())
 		case <-timer.C:
 			close(cmd.timeoutChan)
 			// mark as timeout only if the reason is timeout,
@@ -188,50 +308,50 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 		}
 	}()
 
-	return cmd.errChan
+	return cmd
 }
 
 // Do runs your function in a synchronous manner, blocking until either your function succeeds
 // or an error is returned, including hystrix circuit errors
 func Do(name string, run runFunc, fallback fallbackFunc) error {
-	done := make(chan struct{}, 1)
-
-	r := func() error {
-		err := run()
-		if err != nil {
-			return err
+	runC := func(ctx context.Context) error {
+		return run()
+	}
+	var fallbackC fallbackFuncC
+	if fallback != nil {
+		fallbackC = func(ctx context.Context, err error) error {
+			return fallback(err)
 		}
-
-		done <- struct{}{}
-		return nil
 	}
+	return DoC(context.Background(), name, runC, fallbackC)
+}
 
-	f := func(e error) error {
-		err := fallback(e)
-		if err != nil {
-			return err
-		}
+// DoC runs your function in a synchronous manner like Do, passing the context to your run and
+// fallback functions. It returns the context error once the context is done.
+//
+// The execution has been recorded in the health of the circuit and the request log by the time DoC returns.
+func DoC(ctx context.Context, name string, run runFuncC, fallback fallbackFuncC) error {
+	cmd := goC(ctx, name, run, fallback)
+	<-cmd.completed
 
-		done <- struct{}{}
+	select {
+	case err := <-cmd.errChan:
+		return err
+	default:
 		return nil
 	}
+}
 
-	var errChan chan error
-	if fallback == nil {
-		errChan = Go(name, r, nil)
-	} else {
-		errChan = Go(name, r, f)
-	}
+type commandKey struct{}
 
-	select {
-	case <-done:
-		return nil
-	case err := <-errChan:
-		return err
+// reportEventFromContext reports an event on the command executing the run or fallback function which received the context.
+func reportEventFromContext(ctx context.Context, eventType metricCollector.EventType) {
+	if cmd, ok := ctx.Value(commandKey{}).(*command); ok {
+		cmd.reportEvent(eventType)
 	}
 }
 
-func (c *command) reportEvent(eventType string) {
+func (c *command) reportEvent(eventType metricCollector.EventType) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -250,20 +370,33 @@ func (c *command) isTimedOut() bool {
 // accurate metrics and prevent the fallback from executing more than once.
 func (c *command) errorWithFallback(err error) {
 	c.fallbackOnce.Do(func() {
-		eventType := "failure"
+		eventType := metricCollector.EventFailure
 		if err == ErrCircuitOpen {
-			eventType = "short-circuit"
+			eventType = metricCollector.EventShortCircuit
 		} else if err == ErrMaxConcurrency {
-			eventType = "rejected"
+			eventType = metricCollector.EventRejected
 		} else if err == ErrTimeout {
-			eventType = "timeout"
+			eventType = metricCollector.EventTimeout
+		} else if err == ErrCircuitRamping {
+			eventType = metricCollector.EventRampShortCircuit
+		} else if err == ErrRateLimited {
+			eventType = metricCollector.EventRateLimited
+		} else if err == context.Canceled {
+			eventType = metricCollector.EventContextCanceled
+		} else if err == context.DeadlineExceeded {
+			eventType = metricCollector.EventContextDeadlineExceeded
 		}
 
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+
 		c.reportEvent(eventType)
 		fallbackErr := c.tryFallback(err)
-		if fallbackErr != nil {
-			c.errChan <- fallbackErr
-		}
+
+		c.mu.Lock()
+		c.result = fallbackErr
+		c.mu.Unlock()
 	})
 }
 
@@ -273,17 +406,48 @@ func (c *command) tryFallback(err error) error {
 		return err
 	}
 
-	fallbackErr := c.fallback(err)
+	fallbackErr := c.runFallback(err)
+	if fallbackErr == ErrFallbackTimeout {
+		c.reportEvent(metricCollector.EventFallbackTimeout)
+		return fmt.Errorf("fallback failed with '%v'. run error was '%v'", fallbackErr, err)
+	}
 	if fallbackErr != nil {
-		c.reportEvent("fallback-failure")
+		c.reportEvent(metricCollector.EventFallbackFailure)
 		return fmt.Errorf("fallback failed with '%v'. run error was '%v'", fallbackErr, err)
 	}
 
-	c.reportEvent("fallback-success")
+	c.reportEvent(metricCollector.EventFallbackSuccess)
 
 	return nil
 }
 
+// runFallback executes the fallback, giving up once the FallbackTimeout of the circuit has elapsed.
+// The context of a fallback which times out is canceled, and its result is discarded.
+func (c *command) runFallback(err error) error {
+	timeout := getSettings(c.circuit.Name).FallbackTimeout
+	if timeout <= 0 {
+		return c.fallback(c.ctx, err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.fallback(ctx, err)
+	}()
+
+	select {
+	case fallbackErr := <-done:
+		return fallbackErr
+	case <-ctx.Done():
+		if ctxErr := c.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return ErrFallbackTimeout
+	}
+}
+
 func (c *command) setTicket(t *struct{}) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -312,6 +476,13 @@ func (c *command) setRunDuration(duration time.Duration) {
 	c.runDuration = duration
 }
 
+func (c *command) setQueueDuration(duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queueDuration = duration
+}
+
 func (c *command) getRunDuration() time.Duration {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
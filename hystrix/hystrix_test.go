@@ -1,6 +1,7 @@
 package hystrix
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -309,6 +310,53 @@ func TestSlowFallbackOpenCircuit(t *testing.T) {
 	})
 }
 
+func TestFallbackTimeout(t *testing.T) {
+	Convey("with a command which fails, and whose fallback is slower than the fallback timeout", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{FallbackTimeout: 10})
+
+		fallbackCtxErr := make(chan error, 1)
+		errChan := GoC(context.Background(), "", func(ctx context.Context) error {
+			return fmt.Errorf("run_error")
+		}, func(ctx context.Context, err error) error {
+			<-ctx.Done()
+			fallbackCtxErr <- ctx.Err()
+			return nil
+		})
+
+		Convey("the returned error should contain both the fallback timeout and the run error", func() {
+			err := <-errChan
+			So(err.Error(), ShouldEqual, "fallback failed with 'hystrix: fallback timeout'. run error was 'run_error'")
+
+			Convey("the context of the fallback is canceled", func() {
+				So(<-fallbackCtxErr == context.DeadlineExceeded, ShouldBeTrue)
+			})
+
+			Convey("the fallback is recorded as failed", func() {
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 1)
+				So(cb.metrics.DefaultCollector().FallbackSuccesses().Sum(time.Now()), ShouldEqual, 0)
+				So(cb.metrics.DefaultCollector().FallbackFailures().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("with a command which fails, and whose fallback completes within the fallback timeout", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{FallbackTimeout: 100})
+
+		err := Do("", func() error {
+			return fmt.Errorf("run_error")
+		}, func(err error) error {
+			return nil
+		})
+
+		Convey("no error is returned", func() {
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
 func TestFallbackAfterRejected(t *testing.T) {
 	Convey("with a circuit whose pool is full", t, func() {
 		defer Flush()
@@ -439,6 +487,91 @@ func TestDo(t *testing.T) {
 	})
 }
 
+func TestDoC(t *testing.T) {
+	Convey("with a command which reads from its context", t, func() {
+		defer Flush()
+
+		type ctxKey struct{}
+		ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+		var got interface{}
+		err := DoC(ctx, "", func(ctx context.Context) error {
+			got = ctx.Value(ctxKey{})
+			return nil
+		}, nil)
+
+		Convey("the run function receives the context", func() {
+			So(err, ShouldBeNil)
+			So(got, ShouldEqual, "value")
+		})
+	})
+
+	Convey("with a command whose context is canceled while running", t, func() {
+		defer Flush()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var fallbackErr error
+		err := DoC(ctx, "", func(ctx context.Context) error {
+			cancel()
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}, func(ctx context.Context, err error) error {
+			fallbackErr = err
+			return err
+		})
+
+		Convey("the context error is passed to the fallback and returned", func() {
+			So(fallbackErr, ShouldEqual, context.Canceled)
+			So(err.Error(), ShouldEqual, "fallback failed with 'context canceled'. run error was 'context canceled'")
+
+			Convey("and it does not count against the health of the circuit", func() {
+				time.Sleep(10 * time.Millisecond)
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().NumRequests().Sum(time.Now()), ShouldEqual, 0)
+				So(cb.metrics.DefaultCollector().Errors().Sum(time.Now()), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestGoC(t *testing.T) {
+	Convey("with a command whose context deadline passes while running", t, func() {
+		defer Flush()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		served := make(chan error, 1)
+		GoC(ctx, "goc_deadline", func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}, func(ctx context.Context, err error) error {
+			served <- err
+			return nil
+		})
+
+		Convey("the fallback serves the command without waiting for run", func() {
+			So(<-served == context.DeadlineExceeded, ShouldBeTrue)
+			So(time.Since(start), ShouldBeLessThan, 100*time.Millisecond)
+		})
+	})
+
+	Convey("with a command whose context is already canceled", t, func() {
+		defer Flush()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		errChan := GoC(ctx, "goc_canceled", func(ctx context.Context) error {
+			return ctx.Err()
+		}, nil)
+
+		Convey("the context error is returned", func() {
+			So(<-errChan, ShouldEqual, context.Canceled)
+		})
+	})
+}
+
 func TestMaxConcurrencyWithQueue(t *testing.T) {
 	defer Flush()
 
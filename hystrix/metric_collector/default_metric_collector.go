@@ -12,9 +12,11 @@ import (
 // It is used for for all internal hystrix operations
 // including circuit health checks and metrics sent to the hystrix dashboard.
 //
-// Metric Collectors do not need Mutexes as they are updated by circuits within a locked context.
+// It is updated by every execution of the circuit as it completes, concurrently with the reads of the health
+// checks, the dashboard and the snapshots, so it guards its numbers with a mutex of its own.
 type DefaultMetricCollector struct {
-	mutex *sync.RWMutex
+	mutex   *sync.RWMutex
+	windows RollingWindows
 
 	numRequests *rolling.Number
 	errors      *rolling.Number
@@ -25,6 +27,16 @@ type DefaultMetricCollector struct {
 	rejects       *rolling.Number
 	shortCircuits *rolling.Number
 	timeouts      *rolling.Number
+	retries       *rolling.Number
+	hedges        *rolling.Number
+	hedgeWins     *rolling.Number
+
+	collapsedRequests  *rolling.Number
+	responsesFromCache *rolling.Number
+	staleServed        *rolling.Number
+	staleMisses        *rolling.Number
+	rateLimited        *rolling.Number
+	rampShortCircuits  *rolling.Number
 
 	fallbackSuccesses *rolling.Number
 	fallbackFailures  *rolling.Number
@@ -32,7 +44,7 @@ type DefaultMetricCollector struct {
 	runDuration       *rolling.Timing
 }
 
-func newDefaultMetricCollector(name string, commandGroup string) MetricCollector {
+func newDefaultMetricCollector(name string, commandGroup string) MetricCollectorV2 {
 	m := &DefaultMetricCollector{}
 	m.mutex = &sync.RWMutex{}
 	m.Reset()
@@ -103,6 +115,69 @@ func (d *DefaultMetricCollector) Timeouts() *rolling.Number {
 	return d.timeouts
 }
 
+// Retries returns the rolling number of retries
+func (d *DefaultMetricCollector) Retries() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.retries
+}
+
+// Hedges returns the rolling number of hedged attempts
+func (d *DefaultMetricCollector) Hedges() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.hedges
+}
+
+// HedgeWins returns the rolling number of executions served by a hedged attempt
+func (d *DefaultMetricCollector) HedgeWins() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.hedgeWins
+}
+
+// CollapsedRequests returns the rolling number of requests collapsed into batches
+func (d *DefaultMetricCollector) CollapsedRequests() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.collapsedRequests
+}
+
+// ResponsesFromCache returns the rolling number of responses served from a request cache
+func (d *DefaultMetricCollector) ResponsesFromCache() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.responsesFromCache
+}
+
+// StaleServed returns the rolling number of stale results served by a fallback cache
+func (d *DefaultMetricCollector) StaleServed() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.staleServed
+}
+
+// StaleMisses returns the rolling number of fallbacks which found no stale result
+func (d *DefaultMetricCollector) StaleMisses() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.staleMisses
+}
+
+// RateLimited returns the rolling number of commands rejected by their rate limit
+func (d *DefaultMetricCollector) RateLimited() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.rateLimited
+}
+
+// RampShortCircuits returns the rolling number of requests short-circuited while ramping up
+func (d *DefaultMetricCollector) RampShortCircuits() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.rampShortCircuits
+}
+
 // FallbackSuccesses returns the rolling number of fallback successes
 func (d *DefaultMetricCollector) FallbackSuccesses() *rolling.Number {
 	d.mutex.RLock()
@@ -188,6 +263,69 @@ func (d *DefaultMetricCollector) IncrementTimeouts() {
 	d.timeouts.Increment(1)
 }
 
+// IncrementRetries increments the number of retries seen in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementRetries() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.retries.Increment(1)
+}
+
+// IncrementHedges increments the number of hedged attempts seen in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementHedges() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.hedges.Increment(1)
+}
+
+// IncrementHedgeWins increments the number of executions served by a hedged attempt in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementHedgeWins() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.hedgeWins.Increment(1)
+}
+
+// IncrementCollapsedRequests increments the number of requests collapsed into batches in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementCollapsedRequests() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.collapsedRequests.Increment(1)
+}
+
+// IncrementResponsesFromCache increments the number of responses served from a request cache in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementResponsesFromCache() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.responsesFromCache.Increment(1)
+}
+
+// IncrementStaleServed increments the number of stale results served in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementStaleServed() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.staleServed.Increment(1)
+}
+
+// IncrementStaleMisses increments the number of fallbacks which found no stale result in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementStaleMisses() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.staleMisses.Increment(1)
+}
+
+// IncrementRateLimited increments the number of commands rejected by their rate limit in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementRateLimited() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.rateLimited.Increment(1)
+}
+
+// IncrementRampShortCircuits increments the number of requests short-circuited while ramping up in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementRampShortCircuits() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.rampShortCircuits.Increment(1)
+}
+
 // IncrementFallbackSuccesses increments the number of successful calls to the fallback function in the latest time bucket.
 func (d *DefaultMetricCollector) IncrementFallbackSuccesses() {
 	d.mutex.RLock()
@@ -216,21 +354,58 @@ func (d *DefaultMetricCollector) UpdateRunDuration(runDuration time.Duration) {
 	d.runDuration.Add(runDuration)
 }
 
+// Update increments the counters for every event of the result, and updates the durations.
+func (d *DefaultMetricCollector) Update(result ExecutionResult) {
+	updateV1(d, result)
+}
+
 // Reset resets all metrics in this collector to 0.
 func (d *DefaultMetricCollector) Reset() {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	d.numRequests = rolling.NewNumber()
-	d.errors = rolling.NewNumber()
-	d.successes = rolling.NewNumber()
-	d.rejects = rolling.NewNumber()
-	d.queueSize = rolling.NewNumber()
-	d.shortCircuits = rolling.NewNumber()
-	d.failures = rolling.NewNumber()
-	d.timeouts = rolling.NewNumber()
-	d.fallbackSuccesses = rolling.NewNumber()
-	d.fallbackFailures = rolling.NewNumber()
-	d.totalDuration = rolling.NewTiming()
-	d.runDuration = rolling.NewTiming()
+	d.numRequests = d.newNumber()
+	d.errors = d.newNumber()
+	d.successes = d.newNumber()
+	d.rejects = d.newNumber()
+	d.queueSize = d.newNumber()
+	d.shortCircuits = d.newNumber()
+	d.failures = d.newNumber()
+	d.timeouts = d.newNumber()
+	d.retries = d.newNumber()
+	d.hedges = d.newNumber()
+	d.hedgeWins = d.newNumber()
+	d.collapsedRequests = d.newNumber()
+	d.responsesFromCache = d.newNumber()
+	d.staleServed = d.newNumber()
+	d.staleMisses = d.newNumber()
+	d.rateLimited = d.newNumber()
+	d.rampShortCircuits = d.newNumber()
+	d.fallbackSuccesses = d.newNumber()
+	d.fallbackFailures = d.newNumber()
+	d.totalDuration = d.newTiming()
+	d.runDuration = d.newTiming()
+}
+
+// SetRollingWindows keeps the statistics over the given windows from now on, resetting them.
+func (d *DefaultMetricCollector) SetRollingWindows(windows RollingWindows) {
+	d.mutex.Lock()
+	d.windows = windows
+	d.mutex.Unlock()
+
+	d.Reset()
+}
+
+func (d *DefaultMetricCollector) newNumber() *rolling.Number {
+	if d.windows.StatisticalWindow <= 0 {
+		return rolling.NewNumber()
+	}
+	return rolling.NewNumberWindow(d.windows.StatisticalWindow, d.windows.StatisticalBuckets)
+}
+
+func (d *DefaultMetricCollector) newTiming() *rolling.Timing {
+	if d.windows.PercentileWindow <= 0 {
+		return rolling.NewTiming()
+	}
+	return rolling.NewTimingWindow(d.windows.PercentileWindow, d.windows.PercentileBuckets)
 }
@@ -0,0 +1,296 @@
+package metricCollector
+
+import (
+	"context"
+	"time"
+)
+
+// EventType describes something which happened during the execution of a command.
+type EventType string
+
+const (
+	// EventSuccess is reported when the run function returned no error.
+	EventSuccess EventType = "success"
+	// EventFailure is reported when the run function returned an error.
+	EventFailure EventType = "failure"
+	// EventRejected is reported when the command could not get an execution ticket.
+	EventRejected EventType = "rejected"
+	// EventShortCircuit is reported when the circuit was open.
+	EventShortCircuit EventType = "short-circuit"
+	// EventTimeout is reported when the run function took longer than the timeout.
+	EventTimeout EventType = "timeout"
+	// EventQueued is reported when the command waited for an execution ticket.
+	EventQueued EventType = "queued"
+	// EventRetry is reported for every retry of a failed run function.
+	EventRetry EventType = "retry"
+	// EventHedge is reported for every hedged attempt of a slow run function.
+	EventHedge EventType = "hedge"
+	// EventHedgeWin is reported when a hedged attempt served the command.
+	EventHedgeWin EventType = "hedge-win"
+	// EventCollapsed is reported for every request collapsed into a batch execution.
+	EventCollapsed EventType = "collapsed"
+	// EventResponseFromCache is reported when the response was taken from a request cache.
+	EventResponseFromCache EventType = "response-from-cache"
+	// EventStaleServed is reported when the fallback served a stale result.
+	EventStaleServed EventType = "stale-served"
+	// EventStaleMiss is reported when the fallback found no stale result to serve.
+	EventStaleMiss EventType = "stale-miss"
+	// EventRateLimited is reported when the command was rejected by its rate limit.
+	EventRateLimited EventType = "rate-limited"
+	// EventRampShortCircuit is reported when the command was turned away while the circuit ramps up.
+	EventRampShortCircuit EventType = "ramp-short-circuit"
+	// EventFallbackSuccess is reported when the fallback returned no error.
+	EventFallbackSuccess EventType = "fallback-success"
+	// EventFallbackFailure is reported when the fallback returned an error.
+	EventFallbackFailure EventType = "fallback-failure"
+	// EventFallbackTimeout is reported when the fallback took longer than the fallback timeout.
+	EventFallbackTimeout EventType = "fallback-timeout"
+	// EventContextCanceled is reported when the context of the command was canceled.
+	EventContextCanceled EventType = "context-canceled"
+	// EventContextDeadlineExceeded is reported when the deadline of the context of the command passed.
+	EventContextDeadlineExceeded EventType = "context-deadline-exceeded"
+)
+
+// ExecutionResult describes a single execution of a command, as reported to MetricCollectorV2 implementations.
+type ExecutionResult struct {
+	// Events lists everything which happened during the execution, in order.
+	Events []EventType
+	// Start is when the command was started.
+	Start time.Time
+	// RunDuration is how long the run function took, or zero when it did not complete.
+	RunDuration time.Duration
+	// TotalDuration is how long the command took from its start until its result was reported.
+	TotalDuration time.Duration
+	// QueueDuration is how long the command waited for an execution ticket after being queued.
+	QueueDuration time.Duration
+	// Error is the error the run function failed with, or the error hystrix rejected the command with.
+	// It is nil for successful executions.
+	Error error
+
+	// CircuitOpen is the state of the circuit when the result was reported.
+	CircuitOpen bool
+	// ActiveCount and WaitingCount are the number of commands executing and waiting for a ticket
+	// when the result was reported, out of MaxConcurrentRequests.
+	ActiveCount           int
+	WaitingCount          int
+	MaxConcurrentRequests int
+
+	// Labels are the metric labels carried by the context of the command.
+	Labels map[string]string
+	// Context is the context the command was executed with, so that results can be tied to the traces
+	// of their caller. It may be done by the time the result is reported.
+	Context context.Context
+}
+
+// HasEvent reports whether the event is part of the result.
+func (r ExecutionResult) HasEvent(eventType EventType) bool {
+	for _, e := range r.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// MetricCollectorV2 is the contract for collectors receiving every execution as a single ExecutionResult.
+// New event types are added to the result, so they do not change the interface.
+//
+// Like MetricCollector, implementations do not have to be safe for concurrent updates of a single circuit.
+type MetricCollectorV2 interface {
+	// Update records the result of an execution.
+	Update(result ExecutionResult)
+	// Reset resets the internal counters and timers.
+	Reset()
+}
+
+// V1Adapter wraps a MetricCollector so that it can receive execution results. The optional collector
+// interfaces implemented by the wrapped collector, such as RetryCollector, receive their events too.
+type V1Adapter struct {
+	Collector MetricCollector
+}
+
+// NewV1Adapter wraps a MetricCollector.
+func NewV1Adapter(collector MetricCollector) *V1Adapter {
+	return &V1Adapter{Collector: collector}
+}
+
+// Update increments the counters of the wrapped collector for every event of the result, and updates its durations.
+func (a *V1Adapter) Update(result ExecutionResult) {
+	updateV1(a.Collector, result)
+}
+
+// Reset resets the wrapped collector.
+func (a *V1Adapter) Reset() {
+	a.Collector.Reset()
+}
+
+// SetRollingWindows passes the windows on when the wrapped collector implements RollingWindowCollector.
+func (a *V1Adapter) SetRollingWindows(windows RollingWindows) {
+	if c, ok := a.Collector.(RollingWindowCollector); ok {
+		c.SetRollingWindows(windows)
+	}
+}
+
+// UpdateCircuitState passes the state on when the wrapped collector implements CircuitStateCollector.
+func (a *V1Adapter) UpdateCircuitState(state CircuitState) {
+	if c, ok := a.Collector.(CircuitStateCollector); ok {
+		c.UpdateCircuitState(state)
+	}
+}
+
+func updateV1(collector MetricCollector, result ExecutionResult) {
+	for _, eventType := range result.Events {
+		switch eventType {
+		// granular metrics
+		case EventSuccess:
+			collector.IncrementAttempts()
+			collector.IncrementSuccesses()
+		case EventFailure:
+			collector.IncrementFailures()
+
+			collector.IncrementAttempts()
+			collector.IncrementErrors()
+		case EventRejected:
+			collector.IncrementRejects()
+
+			collector.IncrementAttempts()
+			collector.IncrementErrors()
+		case EventShortCircuit:
+			collector.IncrementShortCircuits()
+
+			collector.IncrementAttempts()
+			collector.IncrementErrors()
+		case EventTimeout:
+			collector.IncrementTimeouts()
+
+			collector.IncrementAttempts()
+			collector.IncrementErrors()
+		case EventQueued:
+			collector.IncrementQueueSize()
+		case EventRampShortCircuit:
+			// turning away requests while ramping up must not open the circuit again
+			if c, ok := collector.(RampCollector); ok {
+				c.IncrementRampShortCircuits()
+			}
+		case EventRateLimited:
+			// like the context events, exceeding our own quota says nothing about the health of the downstream
+			if c, ok := collector.(RateLimitCollector); ok {
+				c.IncrementRateLimited()
+			}
+		case EventRetry:
+			if c, ok := collector.(RetryCollector); ok {
+				c.IncrementRetries()
+			}
+		case EventHedge:
+			if c, ok := collector.(HedgeCollector); ok {
+				c.IncrementHedges()
+			}
+		case EventHedgeWin:
+			if c, ok := collector.(HedgeCollector); ok {
+				c.IncrementHedgeWins()
+			}
+		case EventCollapsed:
+			if c, ok := collector.(CollapserCollector); ok {
+				c.IncrementCollapsedRequests()
+			}
+		case EventResponseFromCache:
+			if c, ok := collector.(CacheCollector); ok {
+				c.IncrementResponsesFromCache()
+			}
+		case EventStaleServed:
+			if c, ok := collector.(StaleCollector); ok {
+				c.IncrementStaleServed()
+			}
+		case EventStaleMiss:
+			if c, ok := collector.(StaleCollector); ok {
+				c.IncrementStaleMisses()
+			}
+
+		// fallback metrics
+		case EventFallbackSuccess:
+			collector.IncrementFallbackSuccesses()
+		case EventFallbackFailure, EventFallbackTimeout:
+			collector.IncrementFallbackFailures()
+		}
+	}
+
+	collector.UpdateTotalDuration(result.TotalDuration)
+	collector.UpdateRunDuration(result.RunDuration)
+}
+
+// V2Adapter wraps a MetricCollectorV2 so that it can be handed out as a MetricCollector. Every increment is
+// reported as a result with a single event. Attempts and errors are implied by the events of a result, so
+// IncrementAttempts and IncrementErrors report nothing, and durations are reported as results without events.
+type V2Adapter struct {
+	Collector MetricCollectorV2
+}
+
+// NewV2Adapter wraps a MetricCollectorV2.
+func NewV2Adapter(collector MetricCollectorV2) *V2Adapter {
+	return &V2Adapter{Collector: collector}
+}
+
+func (a *V2Adapter) event(eventType EventType) {
+	a.Collector.Update(ExecutionResult{Events: []EventType{eventType}})
+}
+
+// IncrementAttempts reports nothing, as attempts are implied by the other events.
+func (a *V2Adapter) IncrementAttempts() {}
+
+// IncrementErrors reports nothing, as errors are implied by the other events.
+func (a *V2Adapter) IncrementErrors() {}
+
+// IncrementQueueSize reports a queued event.
+func (a *V2Adapter) IncrementQueueSize() {
+	a.event(EventQueued)
+}
+
+// IncrementSuccesses reports a success event.
+func (a *V2Adapter) IncrementSuccesses() {
+	a.event(EventSuccess)
+}
+
+// IncrementFailures reports a failure event.
+func (a *V2Adapter) IncrementFailures() {
+	a.event(EventFailure)
+}
+
+// IncrementRejects reports a rejected event.
+func (a *V2Adapter) IncrementRejects() {
+	a.event(EventRejected)
+}
+
+// IncrementShortCircuits reports a short-circuit event.
+func (a *V2Adapter) IncrementShortCircuits() {
+	a.event(EventShortCircuit)
+}
+
+// IncrementTimeouts reports a timeout event.
+func (a *V2Adapter) IncrementTimeouts() {
+	a.event(EventTimeout)
+}
+
+// IncrementFallbackSuccesses reports a fallback-success event.
+func (a *V2Adapter) IncrementFallbackSuccesses() {
+	a.event(EventFallbackSuccess)
+}
+
+// IncrementFallbackFailures reports a fallback-failure event.
+func (a *V2Adapter) IncrementFallbackFailures() {
+	a.event(EventFallbackFailure)
+}
+
+// UpdateTotalDuration reports a result with only its total duration set.
+func (a *V2Adapter) UpdateTotalDuration(timeSinceStart time.Duration) {
+	a.Collector.Update(ExecutionResult{TotalDuration: timeSinceStart})
+}
+
+// UpdateRunDuration reports a result with only its run duration set.
+func (a *V2Adapter) UpdateRunDuration(runDuration time.Duration) {
+	a.Collector.Update(ExecutionResult{RunDuration: runDuration})
+}
+
+// Reset resets the wrapped collector.
+func (a *V2Adapter) Reset() {
+	a.Collector.Reset()
+}
@@ -0,0 +1,117 @@
+package metricCollector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector/mocks"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestV1Adapter(t *testing.T) {
+	Convey("with a v1 collector wrapped in an adapter", t, func() {
+		collector := &mocks.MetricCollector{}
+		adapter := NewV1Adapter(collector)
+
+		Convey("a timeout with a successful fallback increments the v1 counters", func() {
+			collector.On("IncrementTimeouts").Return()
+			collector.On("IncrementAttempts").Return()
+			collector.On("IncrementErrors").Return()
+			collector.On("IncrementFallbackSuccesses").Return()
+			collector.On("UpdateTotalDuration", 2*time.Second).Return()
+			collector.On("UpdateRunDuration", time.Duration(0)).Return()
+
+			adapter.Update(ExecutionResult{
+				Events:        []EventType{EventTimeout, EventFallbackSuccess, EventRetry},
+				TotalDuration: 2 * time.Second,
+			})
+
+			collector.AssertExpectations(t)
+			collector.AssertNumberOfCalls(t, "IncrementAttempts", 1)
+		})
+
+		Convey("the circuit state is ignored when the v1 collector does not record it", func() {
+			adapter.UpdateCircuitState(CircuitState{Open: true})
+
+			collector.AssertExpectations(t)
+		})
+
+		Convey("Reset resets the v1 collector", func() {
+			collector.On("Reset").Return()
+
+			adapter.Reset()
+
+			collector.AssertExpectations(t)
+		})
+	})
+
+	Convey("with a v1 collector recording the circuit state wrapped in an adapter", t, func() {
+		collector := &stateCollector{}
+		adapter := NewV1Adapter(collector)
+
+		Convey("the circuit state is passed on", func() {
+			adapter.UpdateCircuitState(CircuitState{Open: true, ActiveCount: 2, WaitingCount: 1, MaxConcurrentRequests: 10})
+
+			So(collector.state, ShouldResemble, CircuitState{Open: true, ActiveCount: 2, WaitingCount: 1, MaxConcurrentRequests: 10})
+		})
+	})
+
+	Convey("with a collector registered with Register", t, func() {
+		registry := metricCollectorRegistry{lock: Registry.lock}
+		registry.Register(func(name string, commandGroup string) MetricCollector {
+			return &mocks.MetricCollector{}
+		})
+
+		Convey("it is initialized wrapped in an adapter", func() {
+			collectors := registry.InitializeMetricCollectorsV2("", "")
+			So(len(collectors), ShouldEqual, 1)
+			_, ok := collectors[0].(*V1Adapter)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("it is initialized unwrapped as a MetricCollector", func() {
+			collectors := registry.InitializeMetricCollectors("", "")
+			So(len(collectors), ShouldEqual, 1)
+			_, ok := collectors[0].(*mocks.MetricCollector)
+			So(ok, ShouldBeTrue)
+		})
+	})
+
+	Convey("with a collector registered with RegisterV2", t, func() {
+		registry := metricCollectorRegistry{lock: Registry.lock}
+		collector := &resultCollector{}
+		registry.RegisterV2(func(name string, commandGroup string) MetricCollectorV2 {
+			return collector
+		})
+
+		Convey("it is initialized wrapped in an adapter as a MetricCollector", func() {
+			collectors := registry.InitializeMetricCollectors("", "")
+			So(len(collectors), ShouldEqual, 1)
+			adapter, ok := collectors[0].(*V2Adapter)
+			So(ok, ShouldBeTrue)
+
+			adapter.IncrementAttempts()
+			adapter.IncrementSuccesses()
+			So(collector.results, ShouldResemble, []ExecutionResult{{Events: []EventType{EventSuccess}}})
+		})
+	})
+}
+
+type stateCollector struct {
+	mocks.MetricCollector
+	state CircuitState
+}
+
+func (c *stateCollector) UpdateCircuitState(state CircuitState) {
+	c.state = state
+}
+
+type resultCollector struct {
+	results []ExecutionResult
+}
+
+func (c *resultCollector) Update(result ExecutionResult) {
+	c.results = append(c.results, result)
+}
+
+func (c *resultCollector) Reset() {}
@@ -9,22 +9,42 @@ import (
 // collect statistics about the health of the circuit.
 var Registry = metricCollectorRegistry{
 	lock: &sync.RWMutex{},
-	registry: []func(name string, commandGroup string) MetricCollector{
+	registry: []func(name string, commandGroup string) MetricCollectorV2{
 		newDefaultMetricCollector,
 	},
 }
 
 type metricCollectorRegistry struct {
 	lock     *sync.RWMutex
-	registry []func(name string, commandGroup string) MetricCollector
+	registry []func(name string, commandGroup string) MetricCollectorV2
 }
 
 // InitializeMetricCollectors runs the registried MetricCollector Initializers to create an array of MetricCollectors.
+// Collectors registered with RegisterV2 are wrapped in a V2Adapter, unless they implement MetricCollector themselves.
 func (m *metricCollectorRegistry) InitializeMetricCollectors(name string, commandGroup string) []MetricCollector {
+	collectors := m.InitializeMetricCollectorsV2(name, commandGroup)
+
+	metrics := make([]MetricCollector, len(collectors))
+	for i, collector := range collectors {
+		switch c := collector.(type) {
+		case *V1Adapter:
+			metrics[i] = c.Collector
+		case MetricCollector:
+			metrics[i] = c
+		default:
+			metrics[i] = NewV2Adapter(c)
+		}
+	}
+	return metrics
+}
+
+// InitializeMetricCollectorsV2 runs the registried Initializers to create an array of MetricCollectorV2s.
+// Collectors registered with Register are wrapped in a V1Adapter.
+func (m *metricCollectorRegistry) InitializeMetricCollectorsV2(name string, commandGroup string) []MetricCollectorV2 {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	metrics := make([]MetricCollector, len(m.registry))
+	metrics := make([]MetricCollectorV2, len(m.registry))
 	for i, metricCollectorInitializer := range m.registry {
 		metrics[i] = metricCollectorInitializer(name, commandGroup)
 	}
@@ -33,6 +53,13 @@ func (m *metricCollectorRegistry) InitializeMetricCollectors(name string, comman
 
 // Register places a MetricCollector Initializer in the registry maintained by this metricCollectorRegistry.
 func (m *metricCollectorRegistry) Register(initMetricCollector func(string, string) MetricCollector) {
+	m.RegisterV2(func(name string, commandGroup string) MetricCollectorV2 {
+		return NewV1Adapter(initMetricCollector(name, commandGroup))
+	})
+}
+
+// RegisterV2 places a MetricCollectorV2 Initializer in the registry maintained by this metricCollectorRegistry.
+func (m *metricCollectorRegistry) RegisterV2(initMetricCollector func(string, string) MetricCollectorV2) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -72,3 +99,84 @@ type MetricCollector interface {
 	// Reset resets the internal counters and timers.
 	Reset()
 }
+
+// RetryCollector is implemented by collectors which also count the retries made within a single execution.
+// Retries are not attempts of their own, so they do not affect the health of the circuit.
+type RetryCollector interface {
+	// IncrementRetries increments the number of times a failed run function was retried.
+	IncrementRetries()
+}
+
+// HedgeCollector is implemented by collectors which also count hedged attempts of slow executions.
+type HedgeCollector interface {
+	// IncrementHedges increments the number of additional attempts started to hedge a slow run function.
+	IncrementHedges()
+	// IncrementHedgeWins increments the number of executions which were served by a hedged attempt.
+	IncrementHedgeWins()
+}
+
+// CollapserCollector is implemented by collectors which also count the requests collapsed into batches.
+type CollapserCollector interface {
+	// IncrementCollapsedRequests increments the number of requests which were collapsed into a batch execution.
+	IncrementCollapsedRequests()
+}
+
+// CacheCollector is implemented by collectors which also count the responses served from a request cache.
+type CacheCollector interface {
+	// IncrementResponsesFromCache increments the number of responses served from a request cache instead of executing the command.
+	IncrementResponsesFromCache()
+}
+
+// StaleCollector is implemented by collectors which also count the stale results served by a fallback cache.
+type StaleCollector interface {
+	// IncrementStaleServed increments the number of fallbacks which served a stale result.
+	IncrementStaleServed()
+	// IncrementStaleMisses increments the number of fallbacks which found no stale result to serve.
+	IncrementStaleMisses()
+}
+
+// RateLimitCollector is implemented by collectors which also count the commands rejected by a rate limit.
+type RateLimitCollector interface {
+	// IncrementRateLimited increments the number of commands rejected by their rate limit.
+	IncrementRateLimited()
+}
+
+// RampCollector is implemented by collectors which also count the requests turned away while a circuit ramps up after closing.
+type RampCollector interface {
+	// IncrementRampShortCircuits increments the number of requests short-circuited while ramping up.
+	IncrementRampShortCircuits()
+}
+
+// CircuitState describes whether a circuit is open and how busy its pool is at a point in time.
+type CircuitState struct {
+	// Open is true when the circuit is open, or was opened manually.
+	Open bool
+	// ActiveCount and WaitingCount are the number of commands executing and waiting for a ticket,
+	// out of MaxConcurrentRequests.
+	ActiveCount           int
+	WaitingCount          int
+	MaxConcurrentRequests int
+}
+
+// CircuitStateCollector is implemented by collectors which also record the state of the circuit and its pool,
+// typically as gauges.
+type CircuitStateCollector interface {
+	// UpdateCircuitState is called whenever the circuit opens or closes, and periodically in between.
+	UpdateCircuitState(state CircuitState)
+}
+
+// RollingWindows describes the windows over which the statistics of a circuit are kept.
+type RollingWindows struct {
+	// StatisticalWindow is split into StatisticalBuckets for counters.
+	StatisticalWindow  time.Duration
+	StatisticalBuckets int
+	// PercentileWindow is split into PercentileBuckets for durations.
+	PercentileWindow  time.Duration
+	PercentileBuckets int
+}
+
+// RollingWindowCollector is implemented by collectors which keep their statistics over the windows configured for the circuit.
+type RollingWindowCollector interface {
+	// SetRollingWindows is called right after the collector is created, before any metrics are collected.
+	SetRollingWindows(windows RollingWindows)
+}
@@ -1,34 +1,66 @@
 package hystrix
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
 	"github.com/myteksi/hystrix-go/hystrix/rolling"
 )
 
-type commandExecution struct {
-	Types       []string      `json:"types"`
-	Start       time.Time     `json:"start_time"`
-	RunDuration time.Duration `json:"run_duration"`
-}
+// metricQueueSize is how many updates are buffered for each collector before they are dropped.
+const metricQueueSize = 2000
+
+// stateQueueSize is how many circuit states are buffered for each collector before they are dropped.
+const stateQueueSize = 10
 
 type metricExchange struct {
+	// droppedUpdates is accessed atomically, so it is kept first to be 64-bit aligned
+	droppedUpdates uint64
+
 	Name    string
-	Updates chan *commandExecution
-	Mutex   *sync.RWMutex
+	Updates chan *metricCollector.ExecutionResult
+	// Mutex guards the list of collectors.
+	Mutex *sync.RWMutex
 
-	metricCollectors []metricCollector.MetricCollector
+	metricCollectors []metricCollector.MetricCollectorV2
+	queues           []*metricQueue
+	// done stops Monitor and the workers of the queues once the circuit is flushed.
+	done chan struct{}
+}
+
+// metricQueue holds the updates waiting for a single collector, which are run by a worker of its own.
+// The worker is the only caller of the collector, so a slow collector never holds up the circuit.
+type metricQueue struct {
+	collector metricCollector.MetricCollectorV2
+	updates   chan *metricCollector.ExecutionResult
+	// resets holds a pending reset of the collector, resets requested while one is pending are merged into it.
+	resets chan struct{}
+	done   chan struct{}
+	// states is nil unless the collector records the state of the circuit.
+	states         chan metricCollector.CircuitState
+	stateCollector metricCollector.CircuitStateCollector
 }
 
 func newMetricExchange(name string, commandGroup string) *metricExchange {
 	m := &metricExchange{}
 	m.Name = name
 
-	m.Updates = make(chan *commandExecution, 2000)
+	m.Updates = make(chan *metricCollector.ExecutionResult, metricQueueSize)
 	m.Mutex = &sync.RWMutex{}
-	m.metricCollectors = metricCollector.Registry.InitializeMetricCollectors(name, commandGroup)
+	m.done = make(chan struct{})
+	m.metricCollectors = metricCollector.Registry.InitializeMetricCollectorsV2(name, commandGroup)
+	for i, collector := range m.metricCollectors {
+		if c, ok := collector.(metricCollector.RollingWindowCollector); ok {
+			c.SetRollingWindows(rollingWindows(name))
+		}
+		// the default collector is updated inline, see update()
+		if i > 0 {
+			m.queues = append(m.queues, newMetricQueue(collector, m.done))
+		}
+	}
 	m.Reset()
 
 	go m.Monitor()
@@ -36,6 +68,23 @@ func newMetricExchange(name string, commandGroup string) *metricExchange {
 	return m
 }
 
+func newMetricQueue(collector metricCollector.MetricCollectorV2, done chan struct{}) *metricQueue {
+	q := &metricQueue{
+		collector: collector,
+		updates:   make(chan *metricCollector.ExecutionResult, metricQueueSize),
+		resets:    make(chan struct{}, 1),
+		done:      done,
+	}
+	if c, ok := collector.(metricCollector.CircuitStateCollector); ok {
+		q.states = make(chan metricCollector.CircuitState, stateQueueSize)
+		q.stateCollector = c
+	}
+
+	go q.run()
+
+	return q
+}
+
 // The Default Collector function will panic if collectors are not setup to specification.
 func (m *metricExchange) DefaultCollector() *metricCollector.DefaultMetricCollector {
 	if len(m.metricCollectors) < 1 {
@@ -48,79 +97,113 @@ func (m *metricExchange) DefaultCollector() *metricCollector.DefaultMetricCollec
 	return collection
 }
 
-func (m *metricExchange) Monitor() {
-	for update := range m.Updates {
-		// we only grab a read lock to make sure Reset() isn't changing the numbers.
-		m.Mutex.RLock()
-
-		totalDuration := time.Since(update.Start)
-		wg := &sync.WaitGroup{}
-		for _, collector := range m.metricCollectors {
-			wg.Add(1)
-			go m.IncrementMetrics(wg, collector, update, totalDuration)
-		}
-		wg.Wait()
+// update records the result of an execution. The default collector driving circuit health is updated
+// right away, so that the health of the circuit never lags behind or misses an execution, while the
+// other collectors are updated asynchronously.
+func (m *metricExchange) update(result *metricCollector.ExecutionResult) {
+	m.DefaultCollector().Update(*result)
 
-		m.Mutex.RUnlock()
+	m.Mutex.RLock()
+	external := len(m.queues) > 0
+	m.Mutex.RUnlock()
+	if !external {
+		return
 	}
-}
 
-func (m *metricExchange) IncrementMetrics(wg *sync.WaitGroup, collector metricCollector.MetricCollector, update *commandExecution, totalDuration time.Duration) {
-	// granular metrics
-	if update.Types[0] == "success" {
-		collector.IncrementAttempts()
-		collector.IncrementSuccesses()
+	select {
+	case m.Updates <- result:
+	default:
+		// the metric pipeline is at capacity, which is counted rather than logged for every update
+		m.dropUpdate()
 	}
-	if update.Types[0] == "failure" {
-		collector.IncrementFailures()
+}
 
-		collector.IncrementAttempts()
-		collector.IncrementErrors()
+// Monitor hands every update over to the queue of each collector other than the default one, without
+// waiting. A collector which cannot keep up drops its own updates, so it never stalls the others.
+// It returns once the metric exchange is stopped.
+func (m *metricExchange) Monitor() {
+	for {
+		select {
+		case update := <-m.Updates:
+			m.Mutex.RLock()
+			for _, q := range m.queues {
+				select {
+				case q.updates <- update:
+				default:
+					m.dropUpdate()
+				}
+			}
+			m.Mutex.RUnlock()
+		case <-m.done:
+			return
+		}
 	}
-	if update.Types[0] == "rejected" {
-		collector.IncrementRejects()
+}
 
-		collector.IncrementAttempts()
-		collector.IncrementErrors()
-	}
-	if update.Types[0] == "short-circuit" {
-		collector.IncrementShortCircuits()
+// Stop ends Monitor and the workers of the collectors, after which updates no longer reach the collectors
+// other than the default one. It is called when the circuit is flushed.
+func (m *metricExchange) Stop() {
+	close(m.done)
+}
 
-		collector.IncrementAttempts()
-		collector.IncrementErrors()
-	}
-	if update.Types[0] == "timeout" {
-		collector.IncrementTimeouts()
+// updateState hands the state of the circuit over to the queue of each collector recording it, without waiting.
+func (m *metricExchange) updateState(state metricCollector.CircuitState) {
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
 
-		collector.IncrementAttempts()
-		collector.IncrementErrors()
-	}
-	if update.Types[0] == "queued" {
-		collector.IncrementQueueSize()
+	for _, q := range m.queues {
+		if q.states == nil {
+			continue
+		}
+		select {
+		case q.states <- state:
+		default:
+			m.dropUpdate()
+		}
 	}
+}
 
-	if len(update.Types) > 1 {
-		// fallback metrics
-		if update.Types[1] == "fallback-success" {
-			collector.IncrementFallbackSuccesses()
-		}
-		if update.Types[1] == "fallback-failure" {
-			collector.IncrementFallbackFailures()
+func (q *metricQueue) run() {
+	for {
+		select {
+		case update := <-q.updates:
+			q.collector.Update(*update)
+		case state := <-q.states:
+			q.stateCollector.UpdateCircuitState(state)
+		case <-q.resets:
+			q.collector.Reset()
+		case <-q.done:
+			return
 		}
 	}
+}
 
-	collector.UpdateTotalDuration(totalDuration)
-	collector.UpdateRunDuration(update.RunDuration)
+// dropUpdate counts an update which did not reach one of the collectors.
+func (m *metricExchange) dropUpdate() {
+	atomic.AddUint64(&m.droppedUpdates, 1)
+}
 
-	wg.Done()
+// DroppedUpdates returns the number of updates which did not reach one of the asynchronous collectors because
+// the metric pipeline or the collector could not keep up, since the circuit was created. An update dropped
+// before reaching any collector is counted once. The default collector never drops updates.
+func (m *metricExchange) DroppedUpdates() uint64 {
+	return atomic.LoadUint64(&m.droppedUpdates)
 }
 
+// Reset clears the default collector right away, and asks the workers of the other collectors to clear them
+// without waiting, so that it can be called while the circuit is locked.
 func (m *metricExchange) Reset() {
-	m.Mutex.Lock()
-	defer m.Mutex.Unlock()
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
 
-	for _, collector := range m.metricCollectors {
-		collector.Reset()
+	m.DefaultCollector().Reset()
+
+	for _, q := range m.queues {
+		select {
+		case q.resets <- struct{}{}:
+		default:
+			// a reset is already pending
+		}
 	}
 }
 
@@ -152,3 +235,48 @@ func (m *metricExchange) ErrorPercent(now time.Time) int {
 func (m *metricExchange) IsHealthy(now time.Time) bool {
 	return m.ErrorPercent(now) < getSettings(m.Name).ErrorPercentThreshold
 }
+
+// rollingWindows returns the windows configured for the circuit, which apply from its creation on.
+// Settings which were initialized without windows use the defaults.
+func rollingWindows(name string) metricCollector.RollingWindows {
+	settings := getSettings(name)
+	windows := metricCollector.RollingWindows{
+		StatisticalWindow:  settings.MetricsRollingStatisticalWindow,
+		StatisticalBuckets: settings.MetricsRollingBuckets,
+		PercentileWindow:   settings.MetricsRollingPercentileWindow,
+		PercentileBuckets:  settings.MetricsRollingPercentileBuckets,
+	}
+
+	if windows.StatisticalWindow <= 0 || windows.StatisticalBuckets <= 0 {
+		windows.StatisticalWindow = time.Duration(DefaultMetricsRollingStatisticalWindow) * time.Millisecond
+		windows.StatisticalBuckets = DefaultMetricsRollingBuckets
+	}
+	if windows.PercentileWindow <= 0 || windows.PercentileBuckets <= 0 {
+		windows.PercentileWindow = time.Duration(DefaultMetricsRollingPercentileWindow) * time.Millisecond
+		windows.PercentileBuckets = DefaultMetricsRollingPercentileBuckets
+	}
+
+	return windows
+}
+
+type metricLabelsKey struct{}
+
+// WithMetricLabels returns a copy of the context carrying the labels, which are passed to metric collectors
+// along with the results of the commands executed under it. Labels already carried by the context are kept
+// unless they are set again.
+func WithMetricLabels(ctx context.Context, labels map[string]string) context.Context {
+	merged := make(map[string]string)
+	for k, v := range metricLabels(ctx) {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, metricLabelsKey{}, merged)
+}
+
+// metricLabels returns the labels carried by the context, or nil when there are none.
+func metricLabels(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(metricLabelsKey{}).(map[string]string)
+	return labels
+}
@@ -1,25 +1,26 @@
 package hystrix
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 func metricFailingPercent(p int) *metricExchange {
 	m := newMetricExchange("", "")
 	for i := 0; i < 100; i++ {
-		t := "success"
+		t := metricCollector.EventSuccess
 		if i < p {
-			t = "failure"
+			t = metricCollector.EventFailure
 		}
-		m.Updates <- &commandExecution{Types: []string{t}}
+		m.update(&metricCollector.ExecutionResult{Events: []metricCollector.EventType{t}})
 	}
 
-	// Updates needs to be flushed
-	time.Sleep(100 * time.Millisecond)
-
 	return m
 }
 
@@ -43,3 +44,243 @@ func TestErrorPercent(t *testing.T) {
 		})
 	})
 }
+
+func TestRollingWindows(t *testing.T) {
+	Convey("with a metric whose statistical window is 200ms", t, func() {
+		ConfigureCommand("short_window", CommandConfig{MetricsRollingStatisticalWindow: 200, MetricsRollingBuckets: 4})
+		m := newMetricExchange("short_window", "")
+		m.update(&metricCollector.ExecutionResult{Events: []metricCollector.EventType{metricCollector.EventSuccess}})
+
+		Convey("requests should be counted within the window", func() {
+			So(m.Requests().Sum(time.Now()), ShouldEqual, 1)
+			So(m.Requests().Window(), ShouldEqual, 200*time.Millisecond)
+		})
+
+		Convey("requests should be forgotten once the window has passed", func() {
+			time.Sleep(250 * time.Millisecond)
+			So(m.Requests().Sum(time.Now()), ShouldEqual, 0)
+		})
+	})
+}
+
+// addCollector adds a collector to an existing metric exchange.
+func addCollector(m *metricExchange, collector metricCollector.MetricCollectorV2) {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	m.metricCollectors = append(m.metricCollectors, collector)
+	m.queues = append(m.queues, newMetricQueue(collector, m.done))
+}
+
+type blockingCollector struct {
+	unblock chan struct{}
+}
+
+func (c *blockingCollector) Update(result metricCollector.ExecutionResult) {
+	<-c.unblock
+}
+
+func (c *blockingCollector) Reset() {}
+
+func TestSlowCollector(t *testing.T) {
+	Convey("with a metric exchange whose second collector is stuck", t, func() {
+		m := newMetricExchange("slow_collector", "")
+		collector := &blockingCollector{unblock: make(chan struct{})}
+		addCollector(m, collector)
+		defer close(collector.unblock)
+
+		for i := 0; i < metricQueueSize+100; i++ {
+			m.update(&metricCollector.ExecutionResult{Events: []metricCollector.EventType{metricCollector.EventSuccess}})
+			if i%100 == 0 {
+				// let the metric pipeline keep up
+				time.Sleep(time.Millisecond)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		Convey("the default collector should receive every update", func() {
+			So(m.Requests().Sum(time.Now()), ShouldEqual, metricQueueSize+100)
+		})
+
+		Convey("the updates the stuck collector could not queue should be counted", func() {
+			// the collector holds one update while it is stuck, and queues metricQueueSize more
+			So(m.DroppedUpdates(), ShouldBeBetweenOrEqual, 99, 100)
+		})
+	})
+}
+
+func TestSlowCollectorDoesNotLockCircuit(t *testing.T) {
+	Convey("with an open circuit whose second collector is stuck", t, func() {
+		defer Flush()
+		cb, _, err := GetCircuit("slow_collector_circuit")
+		So(err, ShouldBeNil)
+
+		collector := &blockingCollector{unblock: make(chan struct{})}
+		addCollector(cb.metrics, collector)
+		defer close(collector.unblock)
+
+		So(cb.ReportEvent([]string{"failure"}, time.Now(), 0), ShouldBeNil)
+		cb.setOpen()
+
+		Convey("closing the circuit resets the collectors without waiting for it", func() {
+			closed := make(chan struct{})
+			go func() {
+				cb.setClose()
+				close(closed)
+			}()
+
+			var returned bool
+			select {
+			case <-closed:
+				returned = true
+			case <-time.After(time.Second):
+			}
+			So(returned, ShouldBeTrue)
+			So(cb.IsOpen(), ShouldBeFalse)
+		})
+	})
+}
+
+type resultRecorder struct {
+	mutex   sync.Mutex
+	results []metricCollector.ExecutionResult
+}
+
+func (r *resultRecorder) Update(result metricCollector.ExecutionResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.results = append(r.results, result)
+}
+
+func (r *resultRecorder) Reset() {}
+
+func (r *resultRecorder) Results() []metricCollector.ExecutionResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]metricCollector.ExecutionResult(nil), r.results...)
+}
+
+func TestExecutionResult(t *testing.T) {
+	Convey("with a collector receiving the results of a circuit", t, func() {
+		defer Flush()
+		cb, _, err := GetCircuit("execution_result")
+		So(err, ShouldBeNil)
+
+		recorder := &resultRecorder{}
+		addCollector(cb.metrics, recorder)
+
+		Convey("a failing command is reported with its error and labels", func() {
+			ctx := WithMetricLabels(context.Background(), map[string]string{"route": "/users", "method": "GET"})
+			ctx = WithMetricLabels(ctx, map[string]string{"method": "POST"})
+			err := DoC(ctx, "execution_result", func(ctx context.Context) error {
+				return fmt.Errorf("run_error")
+			}, nil)
+			So(err, ShouldNotBeNil)
+			time.Sleep(10 * time.Millisecond)
+
+			results := recorder.Results()
+			So(len(results), ShouldEqual, 1)
+			So(results[0].Events, ShouldResemble, []metricCollector.EventType{metricCollector.EventFailure})
+			So(results[0].Error.Error(), ShouldEqual, "run_error")
+			So(results[0].Labels, ShouldResemble, map[string]string{"route": "/users", "method": "POST"})
+			So(metricLabels(results[0].Context), ShouldResemble, results[0].Labels)
+			So(results[0].CircuitOpen, ShouldBeFalse)
+			So(results[0].MaxConcurrentRequests, ShouldEqual, DefaultMaxConcurrent)
+			So(results[0].TotalDuration, ShouldBeGreaterThan, 0)
+		})
+
+		Convey("a successful command is reported without an error", func() {
+			err := Do("execution_result", func() error {
+				return nil
+			}, nil)
+			So(err, ShouldBeNil)
+			time.Sleep(10 * time.Millisecond)
+
+			results := recorder.Results()
+			So(len(results), ShouldEqual, 1)
+			So(results[0].Events, ShouldResemble, []metricCollector.EventType{metricCollector.EventSuccess})
+			So(results[0].Error, ShouldBeNil)
+			So(results[0].Labels, ShouldBeNil)
+		})
+	})
+}
+
+type stateRecorder struct {
+	resultRecorder
+	states []metricCollector.CircuitState
+}
+
+func (r *stateRecorder) UpdateCircuitState(state metricCollector.CircuitState) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states = append(r.states, state)
+}
+
+func (r *stateRecorder) States() []metricCollector.CircuitState {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]metricCollector.CircuitState(nil), r.states...)
+}
+
+func TestCircuitState(t *testing.T) {
+	Convey("with a collector recording the state of a circuit updated every 10ms", t, func() {
+		defer Flush()
+		ConfigureCommand("circuit_state", CommandConfig{MetricsStateInterval: 10})
+		cb, _, err := GetCircuit("circuit_state")
+		So(err, ShouldBeNil)
+
+		recorder := &stateRecorder{}
+		addCollector(cb.metrics, recorder)
+
+		Convey("the state is sent periodically", func() {
+			time.Sleep(35 * time.Millisecond)
+
+			states := recorder.States()
+			So(len(states), ShouldBeGreaterThanOrEqualTo, 2)
+			So(states[0], ShouldResemble, metricCollector.CircuitState{MaxConcurrentRequests: DefaultMaxConcurrent})
+		})
+	})
+
+	Convey("with periodic state updates disabled", t, func() {
+		defer Flush()
+		ConfigureCommand("circuit_state_disabled", CommandConfig{MetricsStateInterval: -1})
+		cb, _, err := GetCircuit("circuit_state_disabled")
+		So(err, ShouldBeNil)
+
+		recorder := &stateRecorder{}
+		addCollector(cb.metrics, recorder)
+
+		Convey("the state is only sent when the circuit opens and closes", func() {
+			time.Sleep(20 * time.Millisecond)
+			So(len(recorder.States()), ShouldEqual, 0)
+
+			cb.setOpen()
+			cb.setClose()
+			So(cb.toggleForceOpen(true), ShouldBeNil)
+			time.Sleep(5 * time.Millisecond)
+
+			states := recorder.States()
+			So(len(states), ShouldEqual, 3)
+			So(states[0].Open, ShouldBeTrue)
+			So(states[1].Open, ShouldBeFalse)
+			So(states[2].Open, ShouldBeTrue)
+		})
+	})
+}
+
+func TestIncrementMetricsWithSeveralEvents(t *testing.T) {
+	Convey("with a failure served by the fallback", t, func() {
+		defer Flush()
+		cb, _, err := GetCircuit("several_events")
+		So(err, ShouldBeNil)
+		So(cb.ReportEvent([]string{"failure", "fallback-success"}, time.Now(), 0), ShouldBeNil)
+		time.Sleep(50 * time.Millisecond)
+		now := time.Now()
+
+		Convey("both the failure and the fallback success are counted", func() {
+			So(cb.metrics.DefaultCollector().Failures().Sum(now), ShouldEqual, 1)
+			So(cb.metrics.DefaultCollector().Errors().Sum(now), ShouldEqual, 1)
+			So(cb.metrics.DefaultCollector().FallbackSuccesses().Sum(now), ShouldEqual, 1)
+		})
+	})
+}
@@ -38,9 +38,10 @@ func (m *bufferedPoolMetrics) Reset() {
 	m.Mutex.Lock()
 	defer m.Mutex.Unlock()
 
-	m.MaxActiveRequests = rolling.NewNumber()
-	m.MaxWaitingRequests = rolling.NewNumber()
-	m.Executed = rolling.NewNumber()
+	windows := rollingWindows(m.Name)
+	m.MaxActiveRequests = rolling.NewNumberWindow(windows.StatisticalWindow, windows.StatisticalBuckets)
+	m.MaxWaitingRequests = rolling.NewNumberWindow(windows.StatisticalWindow, windows.StatisticalBuckets)
+	m.Executed = rolling.NewNumberWindow(windows.StatisticalWindow, windows.StatisticalBuckets)
 }
 
 func (m *bufferedPoolMetrics) Monitor() {
@@ -0,0 +1,183 @@
+package hystrix
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// NewPrometheusHandler returns a handler exposing the metrics of every circuit in the Prometheus text
+// exposition format, for services which do not use the Prometheus client library.
+func NewPrometheusHandler() *PrometheusHandler {
+	return &PrometheusHandler{}
+}
+
+// PrometheusHandler renders the rolling counts, latency percentiles and pool usage of each circuit on every scrape.
+// Every sample is labelled with the name of its circuit and its command group.
+type PrometheusHandler struct{}
+
+var _ http.Handler = (*PrometheusHandler)(nil)
+
+func (ph *PrometheusHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = rw.Write(renderPrometheus(AllMetrics()))
+}
+
+type sampleFunc func(value float64, labels ...string)
+
+type prometheusWriter struct {
+	buf       bytes.Buffer
+	snapshots []Snapshot
+}
+
+// renderPrometheus renders the snapshots in the Prometheus text exposition format.
+func renderPrometheus(snapshots []Snapshot) []byte {
+	w := &prometheusWriter{snapshots: snapshots}
+
+	w.family("hystrix_circuit_open", "Whether the circuit is open.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(boolValue(s.Open))
+	})
+	w.family("hystrix_circuit_force_open", "Whether the circuit was opened manually.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(boolValue(s.ForceOpen))
+	})
+	w.family("hystrix_circuit_sleep_window_seconds", "How long the circuit waits before probing the backend once open.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(s.EffectiveSleepWindow.Seconds())
+	})
+	w.family("hystrix_rolling_requests", "Number of requests over the rolling statistical window.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(float64(s.Requests))
+	})
+	w.family("hystrix_rolling_errors", "Number of errors over the rolling statistical window.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(float64(s.Errors))
+	})
+	w.family("hystrix_rolling_error_percent", "Percentage of requests which failed over the rolling statistical window.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(float64(s.ErrorPercent))
+	})
+	w.family("hystrix_rolling_events", "Number of events over the rolling statistical window, by event type.", "gauge", func(s Snapshot, add sampleFunc) {
+		for _, e := range eventCounts(s.Counts) {
+			add(float64(e.count), "event", string(e.eventType))
+		}
+	})
+	w.latency("hystrix_run_latency", "How long the run function took over the rolling percentile window", func(s Snapshot) LatencySnapshot {
+		return s.RunLatency
+	})
+	w.latency("hystrix_total_latency", "How long executions took over the rolling percentile window", func(s Snapshot) LatencySnapshot {
+		return s.TotalLatency
+	})
+	w.family("hystrix_pool_max_concurrent_requests", "Number of executions the pool of the circuit allows at a time.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(float64(s.Pool.MaxConcurrentRequests))
+	})
+	w.family("hystrix_pool_active", "Number of executions holding a ticket.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(float64(s.Pool.Active))
+	})
+	w.family("hystrix_pool_waiting", "Number of executions waiting for a ticket.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(float64(s.Pool.Waiting))
+	})
+	w.family("hystrix_pool_rolling_max_active", "Largest number of executions holding a ticket over the rolling statistical window.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(float64(s.Pool.MaxActive))
+	})
+	w.family("hystrix_pool_rolling_executed", "Number of executions started over the rolling statistical window.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(float64(s.Pool.Executed))
+	})
+	w.family("hystrix_dropped_metric_updates_total", "Number of updates which did not reach one of the metric collectors.", "counter", func(s Snapshot, add sampleFunc) {
+		add(float64(s.DroppedMetricUpdates))
+	})
+
+	return w.buf.Bytes()
+}
+
+// family renders a metric family, with the samples added for each snapshot.
+func (w *prometheusWriter) family(name, help, kind string, samples func(s Snapshot, add sampleFunc)) {
+	fmt.Fprintf(&w.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+
+	for _, s := range w.snapshots {
+		circuit, commandGroup := s.Name, s.CommandGroup
+		samples(s, func(value float64, labels ...string) {
+			w.sample(name, append([]string{"circuit", circuit, "command_group", commandGroup}, labels...), value)
+		})
+	}
+}
+
+// latency renders the percentiles and the mean of a latency in seconds.
+func (w *prometheusWriter) latency(name, help string, latency func(s Snapshot) LatencySnapshot) {
+	w.family(name+"_seconds", help+", by quantile.", "gauge", func(s Snapshot, add sampleFunc) {
+		l := latency(s)
+		for _, q := range []struct {
+			quantile string
+			value    time.Duration
+		}{
+			{"0", l.P0}, {"0.25", l.P25}, {"0.5", l.P50}, {"0.75", l.P75}, {"0.9", l.P90},
+			{"0.95", l.P95}, {"0.99", l.P99}, {"0.995", l.P995}, {"1", l.P100},
+		} {
+			add(q.value.Seconds(), "quantile", q.quantile)
+		}
+	})
+	w.family(name+"_mean_seconds", help+", on average.", "gauge", func(s Snapshot, add sampleFunc) {
+		add(latency(s).Mean.Seconds())
+	})
+}
+
+// sample renders a single sample, the labels being given as name and value pairs.
+func (w *prometheusWriter) sample(name string, labels []string, value float64) {
+	w.buf.WriteString(name)
+	w.buf.WriteByte('{')
+	for i := 0; i < len(labels); i += 2 {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		w.buf.WriteString(labels[i])
+		w.buf.WriteString(`="`)
+		w.buf.WriteString(escapeLabelValue(labels[i+1]))
+		w.buf.WriteByte('"')
+	}
+	w.buf.WriteString("} ")
+	w.buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	w.buf.WriteByte('\n')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes the characters which cannot appear as is in a label value. Other characters,
+// such as the "/" and ":" often found in circuit names, are kept.
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type eventCount struct {
+	eventType metricCollector.EventType
+	count     uint64
+}
+
+// eventCounts lists the rolling count of each event. Fallback timeouts are counted as fallback failures.
+func eventCounts(c SnapshotCounts) []eventCount {
+	return []eventCount{
+		{metricCollector.EventSuccess, c.Successes},
+		{metricCollector.EventFailure, c.Failures},
+		{metricCollector.EventRejected, c.Rejects},
+		{metricCollector.EventShortCircuit, c.ShortCircuits},
+		{metricCollector.EventTimeout, c.Timeouts},
+		{metricCollector.EventQueued, c.QueueSize},
+		{metricCollector.EventFallbackSuccess, c.FallbackSuccesses},
+		{metricCollector.EventFallbackFailure, c.FallbackFailures},
+		{metricCollector.EventRetry, c.Retries},
+		{metricCollector.EventHedge, c.Hedges},
+		{metricCollector.EventHedgeWin, c.HedgeWins},
+		{metricCollector.EventCollapsed, c.CollapsedRequests},
+		{metricCollector.EventResponseFromCache, c.ResponsesFromCache},
+		{metricCollector.EventStaleServed, c.StaleServed},
+		{metricCollector.EventStaleMiss, c.StaleMisses},
+		{metricCollector.EventRateLimited, c.RateLimited},
+		{metricCollector.EventRampShortCircuit, c.RampShortCircuits},
+	}
+}
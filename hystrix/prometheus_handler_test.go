@@ -0,0 +1,56 @@
+package hystrix
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	Convey("with a circuit whose name contains / and :", t, func() {
+		defer Flush()
+		ConfigureCommand("api/users:get", CommandConfig{CommandGroup: "users"})
+		_ = Do("api/users:get", func() error {
+			return nil
+		}, nil)
+		time.Sleep(10 * time.Millisecond)
+
+		Convey("a scrape renders its metrics in the text exposition format", func() {
+			req, err := http.NewRequest("GET", "/metrics", nil)
+			So(err, ShouldBeNil)
+			rw := httptest.NewRecorder()
+			NewPrometheusHandler().ServeHTTP(rw, req)
+
+			So(rw.Header().Get("Content-Type"), ShouldStartWith, "text/plain; version=0.0.4")
+			body := rw.Body.String()
+			So(body, ShouldContainSubstring, "# TYPE hystrix_rolling_requests gauge\n")
+			So(body, ShouldContainSubstring, `hystrix_rolling_requests{circuit="api/users:get",command_group="users"} 1`+"\n")
+			So(body, ShouldContainSubstring, `hystrix_rolling_events{circuit="api/users:get",command_group="users",event="success"} 1`+"\n")
+			So(body, ShouldContainSubstring, `hystrix_rolling_events{circuit="api/users:get",command_group="users",event="failure"} 0`+"\n")
+			So(body, ShouldContainSubstring, `hystrix_circuit_open{circuit="api/users:get",command_group="users"} 0`+"\n")
+			So(body, ShouldContainSubstring, `hystrix_run_latency_seconds{circuit="api/users:get",command_group="users",quantile="0.99"} `)
+			So(body, ShouldContainSubstring, `hystrix_pool_max_concurrent_requests{circuit="api/users:get",command_group="users"} 10`+"\n")
+
+			Convey("every sample line is a metric name, its labels and a value", func() {
+				for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+					if strings.HasPrefix(line, "#") {
+						continue
+					}
+					So(line, ShouldStartWith, "hystrix_")
+					So(len(strings.Split(line, "} ")), ShouldEqual, 2)
+				}
+			})
+		})
+	})
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	Convey("label values keep / and : but escape backslashes, quotes and newlines", t, func() {
+		So(escapeLabelValue("api/users:get"), ShouldEqual, "api/users:get")
+		So(escapeLabelValue(`a\b"c`+"\n"), ShouldEqual, `a\\b\"c\n`)
+	})
+}
@@ -0,0 +1,58 @@
+package hystrix
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRampInitialPercent is the percentage of requests admitted right after closing when a RampPolicy does not set one.
+const DefaultRampInitialPercent = 10
+
+// ErrCircuitRamping occurs when a request is turned away because the circuit recently closed and is still
+// ramping up the traffic it admits.
+var ErrCircuitRamping = CircuitError{Message: "circuit ramping"}
+
+// RampPolicy describes how traffic is admitted again after a circuit closes. Instead of admitting every
+// request at once, the admitted fraction grows from InitialPercent to all requests over Duration, and the
+// remaining requests are short-circuited to the fallback.
+type RampPolicy struct {
+	// Duration is how long after closing the circuit it takes to admit all requests again.
+	Duration time.Duration
+	// InitialPercent is the percentage of requests admitted right after closing. If 0, defaults to DefaultRampInitialPercent.
+	InitialPercent float64
+	// Exponential doubles the admitted fraction at a constant pace, instead of growing it linearly.
+	Exponential bool
+}
+
+// admitted returns the fraction of requests to admit, between 0 and 1, once the given time has passed since closing.
+func (p *RampPolicy) admitted(elapsed time.Duration) float64 {
+	if elapsed >= p.Duration {
+		return 1
+	}
+
+	initial := p.InitialPercent
+	if initial <= 0 {
+		initial = DefaultRampInitialPercent
+	}
+	initial = math.Min(initial/100, 1)
+
+	progress := float64(elapsed) / float64(p.Duration)
+	if p.Exponential {
+		return initial * math.Pow(1/initial, progress)
+	}
+	return initial + (1-initial)*progress
+}
+
+// allowRamp is checked after AllowRequest, turning away part of the requests while the circuit ramps up after closing.
+func (circuit *CircuitBreaker) allowRamp() bool {
+	policy := getSettings(circuit.Name).Ramp
+	closedTime := atomic.LoadInt64(&circuit.closedTime)
+	if policy == nil || policy.Duration <= 0 || closedTime == 0 {
+		return true
+	}
+
+	admitted := policy.admitted(time.Duration(time.Now().UnixNano() - closedTime))
+	return admitted >= 1 || rand.Float64() < admitted
+}
@@ -0,0 +1,84 @@
+package hystrix
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRampPolicy(t *testing.T) {
+	Convey("with a linear ramp over 10 seconds starting at 10%", t, func() {
+		p := &RampPolicy{Duration: 10 * time.Second}
+
+		Convey("the admitted fraction grows linearly", func() {
+			So(p.admitted(0), ShouldAlmostEqual, 0.1)
+			So(p.admitted(5*time.Second), ShouldAlmostEqual, 0.55)
+			So(p.admitted(10*time.Second), ShouldEqual, 1)
+		})
+	})
+
+	Convey("with an exponential ramp over 10 seconds starting at 1%", t, func() {
+		p := &RampPolicy{Duration: 10 * time.Second, InitialPercent: 1, Exponential: true}
+
+		Convey("the admitted fraction grows exponentially", func() {
+			So(p.admitted(0), ShouldAlmostEqual, 0.01)
+			So(p.admitted(5*time.Second), ShouldAlmostEqual, 0.1)
+			So(p.admitted(20*time.Second), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestRamp(t *testing.T) {
+	Convey("with a circuit which ramps up after closing", t, func() {
+		defer Flush()
+		ConfigureCommand("ramping", CommandConfig{RampDuration: 60000, RampInitialPercent: 1})
+		cb, _, _ := GetCircuit("ramping")
+
+		Convey("a circuit which never opened admits all requests", func() {
+			So(Do("ramping", func() error { return nil }, nil), ShouldBeNil)
+		})
+
+		Convey("and the circuit just closed", func() {
+			cb.setOpen()
+			cb.setClose()
+
+			Convey("most requests are short-circuited through the fallback", func() {
+				ramping := 0
+				for i := 0; i < 10; i++ {
+					err := Do("ramping", func() error { return nil }, func(err error) error {
+						if err == ErrCircuitRamping {
+							ramping++
+						}
+						return nil
+					})
+					So(err, ShouldBeNil)
+				}
+				So(ramping, ShouldBeGreaterThan, 0)
+
+				Convey("and recorded without affecting health", func() {
+					So(cb.metrics.DefaultCollector().RampShortCircuits().Sum(time.Now()), ShouldEqual, ramping)
+					So(cb.metrics.DefaultCollector().Errors().Sum(time.Now()), ShouldEqual, 0)
+				})
+			})
+
+			Convey("and reopened during the ramp", func() {
+				cb.setOpen()
+				So(atomic.LoadInt64(&cb.closedTime), ShouldEqual, 0)
+
+				Convey("the probe runs once the sleep window passed", func() {
+					passSleepWindow(cb)
+					ran := false
+					err := Do("ramping", func() error {
+						ran = true
+						return nil
+					}, nil)
+					So(err, ShouldBeNil)
+					So(ran, ShouldBeTrue)
+					So(cb.IsOpen(), ShouldBeFalse)
+				})
+			})
+		})
+	})
+}
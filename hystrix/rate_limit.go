@@ -0,0 +1,84 @@
+package hystrix
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited occurs when a command is executed more often than the RateLimit configured for it.
+var ErrRateLimited = CircuitError{Message: "rate limited"}
+
+// rateLimiter is a token bucket shared by all commands with the same rate limit key.
+type rateLimiter struct {
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+var rateLimiters = make(map[string]*rateLimiter)
+var rateLimitersMutex sync.Mutex
+
+// allowRate reports whether the command may execute under its rate limit, taking a token when it may.
+// The rate and burst are read from the settings on every call, so that limits can be changed at runtime.
+func allowRate(name string) bool {
+	settings := getSettings(name)
+	if settings.RateLimit <= 0 {
+		return true
+	}
+
+	key := settings.RateLimitKey
+	if key == "" {
+		key = name
+	}
+
+	return getRateLimiter(key).allow(time.Now(), settings.RateLimit, rateLimitBurst(settings))
+}
+
+// rateLimitBurst defaults to one second worth of tokens when no burst is configured.
+func rateLimitBurst(settings *Settings) float64 {
+	if settings.RateLimitBurst > 0 {
+		return float64(settings.RateLimitBurst)
+	}
+	return math.Max(math.Ceil(settings.RateLimit), 1)
+}
+
+func getRateLimiter(key string) *rateLimiter {
+	rateLimitersMutex.Lock()
+	defer rateLimitersMutex.Unlock()
+
+	limiter, ok := rateLimiters[key]
+	if !ok {
+		limiter = &rateLimiter{}
+		rateLimiters[key] = limiter
+	}
+	return limiter
+}
+
+func flushRateLimiters() {
+	rateLimitersMutex.Lock()
+	defer rateLimitersMutex.Unlock()
+
+	rateLimiters = make(map[string]*rateLimiter)
+}
+
+func (r *rateLimiter) allow(now time.Time, rate float64, burst float64) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.last.IsZero() {
+		// a new bucket starts full
+		r.tokens = burst
+	} else {
+		r.tokens += now.Sub(r.last).Seconds() * rate
+	}
+	r.tokens = math.Min(r.tokens, burst)
+	r.last = now
+
+	if r.tokens < 1 {
+		return false
+	}
+
+	r.tokens--
+	return true
+}
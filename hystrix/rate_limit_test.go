@@ -0,0 +1,95 @@
+package hystrix
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimiter(t *testing.T) {
+	Convey("with a new bucket allowing 10 per second and bursts of 2", t, func() {
+		r := &rateLimiter{}
+		now := time.Now()
+
+		Convey("the burst is allowed at once", func() {
+			So(r.allow(now, 10, 2), ShouldBeTrue)
+			So(r.allow(now, 10, 2), ShouldBeTrue)
+
+			Convey("and further executions are rejected", func() {
+				So(r.allow(now, 10, 2), ShouldBeFalse)
+			})
+
+			Convey("and tokens are refilled at the rate", func() {
+				So(r.allow(now.Add(100*time.Millisecond), 10, 2), ShouldBeTrue)
+				So(r.allow(now.Add(100*time.Millisecond), 10, 2), ShouldBeFalse)
+			})
+
+			Convey("and refilled tokens never exceed the burst", func() {
+				later := now.Add(time.Minute)
+				So(r.allow(later, 10, 2), ShouldBeTrue)
+				So(r.allow(later, 10, 2), ShouldBeTrue)
+				So(r.allow(later, 10, 2), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	Convey("with commands limited to 1 execution per second", t, func() {
+		defer Flush()
+		ConfigureCommand("limited", CommandConfig{RateLimit: 1})
+
+		Convey("the first execution succeeds", func() {
+			So(Do("limited", func() error { return nil }, nil), ShouldBeNil)
+
+			Convey("and the next one is rejected through its fallback", func() {
+				var fallbackErr error
+				err := Do("limited", func() error { return nil }, func(err error) error {
+					fallbackErr = err
+					return nil
+				})
+				So(err, ShouldBeNil)
+				So(fallbackErr, ShouldResemble, ErrRateLimited)
+
+				Convey("and the rejection is recorded without affecting health", func() {
+					time.Sleep(10 * time.Millisecond)
+					cb, _, _ := GetCircuit("limited")
+					So(cb.metrics.DefaultCollector().RateLimited().Sum(time.Now()), ShouldEqual, 1)
+					So(cb.metrics.DefaultCollector().Errors().Sum(time.Now()), ShouldEqual, 0)
+				})
+			})
+
+			Convey("and raising the limit takes effect right away", func() {
+				ConfigureCommand("limited", CommandConfig{RateLimit: 1000})
+				time.Sleep(10 * time.Millisecond)
+				So(Do("limited", func() error { return nil }, nil), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("with commands sharing a rate limit key", t, func() {
+		defer Flush()
+		ConfigureCommand("shared1", CommandConfig{RateLimit: 1, RateLimitKey: "shared"})
+		ConfigureCommand("shared2", CommandConfig{RateLimit: 1, RateLimitKey: "shared"})
+
+		Convey("executions of either command take from the same bucket", func() {
+			So(DoC(context.Background(), "shared1", func(ctx context.Context) error { return nil }, nil), ShouldBeNil)
+			So(DoC(context.Background(), "shared2", func(ctx context.Context) error { return nil }, nil), ShouldResemble, ErrRateLimited)
+		})
+	})
+	Convey("with a command limited to 1 execution every 2 seconds", t, func() {
+		defer Flush()
+		ConfigureCommand("fractional", CommandConfig{RateLimit: 0.5})
+
+		Convey("the fractional limit is kept", func() {
+			So(getSettings("fractional").RateLimit, ShouldEqual, 0.5)
+		})
+
+		Convey("the burst allows a single execution", func() {
+			So(Do("fractional", func() error { return nil }, nil), ShouldBeNil)
+			So(DoC(context.Background(), "fractional", func(ctx context.Context) error { return nil }, nil), ShouldResemble, ErrRateLimited)
+		})
+	})
+}
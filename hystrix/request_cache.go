@@ -0,0 +1,166 @@
+package hystrix
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+type valueFuncC func(context.Context) (interface{}, error)
+type valueFallbackFuncC func(context.Context, error) (interface{}, error)
+
+// requestCache holds the responses of commands executed within a single inbound request.
+type requestCache struct {
+	mutex   sync.Mutex
+	entries map[string]*requestCacheEntry
+}
+
+type requestCacheEntry struct {
+	ready chan struct{}
+	value interface{}
+	err   error
+	// discarded is set when the response belongs to the caller which produced it, because its context was done
+	// or it panicked. Callers waiting for it execute the command again instead.
+	discarded bool
+}
+
+type requestCacheKey struct{}
+
+// WithRequestCache returns a copy of the context carrying an empty request cache. Commands executed with DoCached
+// under the returned context share their responses, so it should be called once at the start of an inbound request.
+func WithRequestCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestCacheKey{}, &requestCache{
+		entries: make(map[string]*requestCacheEntry),
+	})
+}
+
+// DoCached runs your function in a synchronous manner like DoC, returning the value it produced. When the context
+// carries a request cache and RequestCacheEnabled is set for the command, commands with the same name and cache key
+// are executed only once and later callers receive the same value and error, recorded as a "response-from-cache" event.
+// Errors caused by the context of the first caller being done are not shared: the command is executed again for the
+// callers waiting for it.
+func DoCached(ctx context.Context, name string, cacheKey string, run valueFuncC, fallback valueFallbackFuncC) (interface{}, error) {
+	cache, ok := ctx.Value(requestCacheKey{}).(*requestCache)
+	if !ok || cacheKey == "" || !getSettings(name).RequestCacheEnabled {
+		return doValue(ctx, name, run, fallback)
+	}
+
+	start := time.Now()
+	key := name + "\x00" + cacheKey
+	var entry *requestCacheEntry
+	for {
+		var created bool
+		entry, created = cache.entry(key)
+		if created {
+			return cache.fill(ctx, key, entry, func() (interface{}, error) {
+				return doValue(ctx, name, run, fallback)
+			})
+		}
+
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !entry.discarded {
+			break
+		}
+	}
+
+	circuit, _, err := GetCircuit(name)
+	if err != nil {
+		return nil, err
+	}
+	err = circuit.reportExecution(&metricCollector.ExecutionResult{
+		Events:  []metricCollector.EventType{metricCollector.EventResponseFromCache},
+		Start:   start,
+		Labels:  metricLabels(ctx),
+		Context: ctx,
+	})
+	if err != nil {
+		log.Print(err)
+	}
+	logRequest(ctx, name, []metricCollector.EventType{metricCollector.EventResponseFromCache}, 0)
+
+	return entry.value, entry.err
+}
+
+// entry returns the entry for the key, and whether the caller created it and is responsible for filling it.
+func (c *requestCache) entry(key string) (*requestCacheEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		return entry, false
+	}
+
+	entry := &requestCacheEntry{ready: make(chan struct{})}
+	c.entries[key] = entry
+	return entry, true
+}
+
+// fill stores the response of the command in the entry and wakes up the callers waiting for it. A response tied to
+// the caller, because its context is done or the command panicked, is discarded and its entry removed, so that the
+// next caller executes the command again.
+func (c *requestCache) fill(ctx context.Context, key string, entry *requestCacheEntry, do func() (interface{}, error)) (interface{}, error) {
+	entry.discarded = true
+	defer func() {
+		if entry.discarded {
+			c.mutex.Lock()
+			if c.entries[key] == entry {
+				delete(c.entries, key)
+			}
+			c.mutex.Unlock()
+		}
+		close(entry.ready)
+	}()
+
+	entry.value, entry.err = do()
+	entry.discarded = entry.err != nil && ctx.Err() != nil
+	return entry.value, entry.err
+}
+
+// doValue executes the command like DoC, passing on the value produced by the run or fallback function.
+func doValue(ctx context.Context, name string, run valueFuncC, fallback valueFallbackFuncC) (interface{}, error) {
+	// buffered so that functions completing after the command returned never block
+	values := make(chan interface{}, 1)
+
+	runC := func(ctx context.Context) error {
+		value, err := run(ctx)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case values <- value:
+		default:
+		}
+		return nil
+	}
+
+	var fallbackC fallbackFuncC
+	if fallback != nil {
+		fallbackC = func(ctx context.Context, err error) error {
+			value, fallbackErr := fallback(ctx, err)
+			if fallbackErr != nil {
+				return fallbackErr
+			}
+
+			select {
+			case values <- value:
+			default:
+			}
+			return nil
+		}
+	}
+
+	err := DoC(ctx, name, runC, fallbackC)
+	if err != nil {
+		return nil, err
+	}
+
+	return <-values, nil
+}
@@ -0,0 +1,129 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func countingRun(executions *int32) valueFuncC {
+	return func(ctx context.Context) (interface{}, error) {
+		return atomic.AddInt32(executions, 1), nil
+	}
+}
+
+func TestDoCached(t *testing.T) {
+	Convey("within a request cache", t, func() {
+		defer Flush()
+		ctx := WithRequestCache(context.Background())
+		executions := int32(0)
+
+		Convey("commands with the same name and cache key execute once", func() {
+			first, err := DoCached(ctx, "", "key", countingRun(&executions), nil)
+			So(err, ShouldBeNil)
+			second, err := DoCached(ctx, "", "key", countingRun(&executions), nil)
+			So(err, ShouldBeNil)
+
+			So(first, ShouldEqual, 1)
+			So(second, ShouldEqual, 1)
+			So(atomic.LoadInt32(&executions), ShouldEqual, 1)
+
+			Convey("and the cache hit is recorded", func() {
+				time.Sleep(10 * time.Millisecond)
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().ResponsesFromCache().Sum(time.Now()), ShouldEqual, 1)
+				So(cb.metrics.DefaultCollector().Successes().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+
+		Convey("commands with different cache keys execute separately", func() {
+			_, _ = DoCached(ctx, "", "key1", countingRun(&executions), nil)
+			_, _ = DoCached(ctx, "", "key2", countingRun(&executions), nil)
+
+			So(atomic.LoadInt32(&executions), ShouldEqual, 2)
+		})
+
+		Convey("errors are shared as well", func() {
+			run := func(ctx context.Context) (interface{}, error) {
+				atomic.AddInt32(&executions, 1)
+				return nil, fmt.Errorf("run_error")
+			}
+			_, err1 := DoCached(ctx, "", "key", run, nil)
+			_, err2 := DoCached(ctx, "", "key", run, nil)
+
+			So(err1.Error(), ShouldEqual, "run_error")
+			So(err2.Error(), ShouldEqual, "run_error")
+			So(atomic.LoadInt32(&executions), ShouldEqual, 1)
+		})
+
+		Convey("errors of a caller whose context is canceled are not shared", func() {
+			canceled, cancel := context.WithCancel(ctx)
+			started := make(chan struct{})
+			canceledErr := make(chan error, 1)
+			go func() {
+				_, err := DoCached(canceled, "", "key", func(ctx context.Context) (interface{}, error) {
+					close(started)
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}, nil)
+				canceledErr <- err
+			}()
+			<-started
+
+			waiting := make(chan collapsedResponse, 1)
+			go func() {
+				value, err := DoCached(ctx, "", "key", countingRun(&executions), nil)
+				waiting <- collapsedResponse{value: value, err: err}
+			}()
+			cancel()
+
+			So(<-canceledErr == context.Canceled, ShouldBeTrue)
+			response := <-waiting
+			So(response.err, ShouldBeNil)
+			So(response.value, ShouldEqual, 1)
+		})
+
+		Convey("and the request cache is disabled for the command", func() {
+			disabled := false
+			ConfigureCommand("", CommandConfig{RequestCacheEnabled: &disabled})
+
+			Convey("every command executes", func() {
+				_, _ = DoCached(ctx, "", "key", countingRun(&executions), nil)
+				_, _ = DoCached(ctx, "", "key", countingRun(&executions), nil)
+
+				So(atomic.LoadInt32(&executions), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("without a request cache", t, func() {
+		defer Flush()
+		executions := int32(0)
+
+		Convey("every command executes", func() {
+			_, _ = DoCached(context.Background(), "", "key", countingRun(&executions), nil)
+			_, _ = DoCached(context.Background(), "", "key", countingRun(&executions), nil)
+
+			So(atomic.LoadInt32(&executions), ShouldEqual, 2)
+		})
+	})
+
+	Convey("with a failing command whose fallback provides a value", t, func() {
+		defer Flush()
+
+		value, err := DoCached(WithRequestCache(context.Background()), "", "key", func(ctx context.Context) (interface{}, error) {
+			return nil, fmt.Errorf("run_error")
+		}, func(ctx context.Context, err error) (interface{}, error) {
+			return "fallback", nil
+		})
+
+		Convey("the fallback value is returned", func() {
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, "fallback")
+		})
+	})
+}
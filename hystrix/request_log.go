@@ -0,0 +1,109 @@
+package hystrix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// RequestLogEntry describes a single command executed within an inbound request.
+type RequestLogEntry struct {
+	Name        string
+	Events      []metricCollector.EventType
+	RunDuration time.Duration
+	// Cached is set when the response was taken from the request cache instead of executing the command
+	Cached bool
+	// Collapsed is set when the request was executed as part of a collapsed batch
+	Collapsed bool
+}
+
+// RequestLog records the commands executed within a single inbound request, in the order they completed.
+type RequestLog struct {
+	mutex   sync.Mutex
+	entries []RequestLogEntry
+}
+
+type requestLogKey struct{}
+
+// WithRequestLog returns a copy of the context carrying an empty request log. Commands executed under the
+// returned context are recorded in it, so it should be called once at the start of an inbound request.
+func WithRequestLog(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestLogKey{}, &RequestLog{})
+}
+
+// GetRequestLog returns the request log carried by the context, or nil when there is none.
+func GetRequestLog(ctx context.Context) *RequestLog {
+	requestLog, _ := ctx.Value(requestLogKey{}).(*RequestLog)
+	return requestLog
+}
+
+// Entries returns a copy of the commands recorded so far. A nil log has no entries.
+func (l *RequestLog) Entries() []RequestLogEntry {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return append([]RequestLogEntry(nil), l.entries...)
+}
+
+// String summarizes the recorded commands for access logs and response headers, such as
+// "get_user[success][12ms], get_order[timeout, fallback-success][1000ms]x2". Identical
+// entries are counted in the position of their first occurrence. A nil log is summarized as "".
+func (l *RequestLog) String() string {
+	var summaries []string
+	counts := make(map[string]int)
+	for _, entry := range l.Entries() {
+		events := make([]string, len(entry.Events))
+		for i, eventType := range entry.Events {
+			events[i] = string(eventType)
+		}
+		summary := fmt.Sprintf("%s[%s][%dms]", entry.Name, strings.Join(events, ", "), int64(entry.RunDuration/time.Millisecond))
+		if counts[summary] == 0 {
+			summaries = append(summaries, summary)
+		}
+		counts[summary]++
+	}
+
+	var buf bytes.Buffer
+	for i, summary := range summaries {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(summary)
+		if counts[summary] > 1 {
+			fmt.Fprintf(&buf, "x%d", counts[summary])
+		}
+	}
+	return buf.String()
+}
+
+func (l *RequestLog) add(entry RequestLogEntry) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.entries = append(l.entries, entry)
+}
+
+// logRequest records the command in the request log carried by the context, if RequestLogEnabled is set for it.
+func logRequest(ctx context.Context, name string, events []metricCollector.EventType, runDuration time.Duration) {
+	requestLog := GetRequestLog(ctx)
+	if requestLog == nil || !getSettings(name).RequestLogEnabled {
+		return
+	}
+
+	requestLog.add(RequestLogEntry{
+		Name:        name,
+		Events:      events,
+		RunDuration: runDuration,
+		Cached:      hasEvent(events, metricCollector.EventResponseFromCache),
+		Collapsed:   hasEvent(events, metricCollector.EventCollapsed),
+	})
+}
@@ -0,0 +1,109 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestLog(t *testing.T) {
+	Convey("within a request log", t, func() {
+		defer Flush()
+		ctx := WithRequestLog(context.Background())
+
+		Convey("executed commands are recorded with their events", func() {
+			_ = DoC(ctx, "good", func(ctx context.Context) error {
+				return nil
+			}, nil)
+			_ = DoC(ctx, "bad", func(ctx context.Context) error {
+				return fmt.Errorf("run_error")
+			}, func(ctx context.Context, err error) error {
+				return nil
+			})
+
+			entries := GetRequestLog(ctx).Entries()
+			So(len(entries), ShouldEqual, 2)
+			So(entries[0].Name, ShouldEqual, "good")
+			So(entries[0].Events, ShouldResemble, []metricCollector.EventType{metricCollector.EventSuccess})
+			So(entries[1].Name, ShouldEqual, "bad")
+			So(entries[1].Events, ShouldResemble, []metricCollector.EventType{metricCollector.EventFailure, metricCollector.EventFallbackSuccess})
+			So(GetRequestLog(ctx).String(), ShouldEqual, "good[success][0ms], bad[failure, fallback-success][0ms]")
+		})
+
+		Convey("cached responses are flagged", func() {
+			ctx = WithRequestCache(ctx)
+			_, _ = DoCached(ctx, "cached", "key", staleRun("value", nil), nil)
+			_, _ = DoCached(ctx, "cached", "key", staleRun("value", nil), nil)
+
+			entries := GetRequestLog(ctx).Entries()
+			So(len(entries), ShouldEqual, 2)
+			So(entries[0].Cached, ShouldBeFalse)
+			So(entries[1].Cached, ShouldBeTrue)
+		})
+
+		Convey("collapsed requests are flagged", func() {
+			c := NewCollapser("collapsed", CollapserConfig{Window: 10 * time.Millisecond}, func(ctx context.Context, keys []string) (map[string]CollapsedResult, error) {
+				return map[string]CollapsedResult{"key": {Value: "value"}}, nil
+			})
+			_, err := c.Do(ctx, "key")
+			So(err, ShouldBeNil)
+
+			entries := GetRequestLog(ctx).Entries()
+			So(len(entries), ShouldEqual, 1)
+			So(entries[0].Collapsed, ShouldBeTrue)
+		})
+
+		Convey("collapsed requests canceled before their batch completes are recorded", func() {
+			c := NewCollapser("collapsed_canceled", CollapserConfig{Window: time.Minute}, func(ctx context.Context, keys []string) (map[string]CollapsedResult, error) {
+				return nil, nil
+			})
+			canceled, cancel := context.WithCancel(ctx)
+			cancel()
+			_, err := c.Do(canceled, "key")
+			So(err == context.Canceled, ShouldBeTrue)
+
+			entries := GetRequestLog(ctx).Entries()
+			So(len(entries), ShouldEqual, 1)
+			So(entries[0].Collapsed, ShouldBeTrue)
+			So(entries[0].Events, ShouldResemble, []metricCollector.EventType{metricCollector.EventCollapsed, metricCollector.EventContextCanceled})
+		})
+
+		Convey("identical entries are counted in the summary", func() {
+			for i := 0; i < 3; i++ {
+				_ = DoC(ctx, "repeated", func(ctx context.Context) error {
+					return nil
+				}, nil)
+			}
+
+			So(GetRequestLog(ctx).String(), ShouldEqual, "repeated[success][0ms]x3")
+		})
+
+		Convey("and the request log is disabled for the command", func() {
+			disabled := false
+			ConfigureCommand("unlogged", CommandConfig{RequestLogEnabled: &disabled})
+
+			Convey("commands are not recorded", func() {
+				_ = DoC(ctx, "unlogged", func(ctx context.Context) error {
+					return nil
+				}, nil)
+
+				So(GetRequestLog(ctx).Entries(), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("without a request log", t, func() {
+		Convey("no request log is returned", func() {
+			So(GetRequestLog(context.Background()), ShouldBeNil)
+		})
+
+		Convey("the nil request log is empty", func() {
+			So(GetRequestLog(context.Background()).Entries(), ShouldBeEmpty)
+			So(GetRequestLog(context.Background()).String(), ShouldEqual, "")
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package hystrix
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// DefaultRetryMultiplier is the factor applied to the backoff after each retry when a RetryPolicy does not set one.
+const DefaultRetryMultiplier = 2
+
+// RetryPolicy describes how a failing run function is retried within a single command execution.
+// Retries hold on to the execution ticket of the command and stop once the command times out or its
+// context is done. Only the outcome of the final attempt counts towards the health of the circuit.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times run is called, including the first attempt.
+	MaxAttempts int
+	// InitialBackoff is how long to wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts. 0 means no cap.
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after every retry. If 0, defaults to DefaultRetryMultiplier.
+	Multiplier float64
+	// Jitter randomly shortens each wait by up to this fraction of it, between 0 and 1.
+	Jitter float64
+	// Retryable reports whether an error returned by run is worth retrying. If nil, every error is retried.
+	Retryable func(error) bool
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable == nil {
+		return true
+	}
+	return p.Retryable(err)
+}
+
+// backoff returns how long to wait before the given retry, starting at 0 for the first retry.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = DefaultRetryMultiplier
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * p.Jitter * rand.Float64()
+	}
+
+	return time.Duration(backoff)
+}
+
+// runWithRetry calls the run function, retrying failed attempts according to the RetryPolicy of the circuit.
+// Every retry is reported as a "retry" event.
+func (c *command) runWithRetry(ctx context.Context) error {
+	err := c.runAttempt(ctx)
+
+	policy := getSettings(c.circuit.Name).Retry
+	if policy == nil {
+		return err
+	}
+
+	for retry := 0; err != nil && retry+1 < policy.MaxAttempts && policy.retryable(err); retry++ {
+		timer := time.NewTimer(policy.backoff(retry))
+		select {
+		case <-timer.C:
+		case <-c.timeoutChan:
+			// timed out or canceled, the result is discarded anyway
+			timer.Stop()
+			return err
+		}
+
+		// with a short backoff both channels can be ready, and select picks either of them
+		select {
+		case <-c.timeoutChan:
+			return err
+		default:
+		}
+
+		c.reportEvent(metricCollector.EventRetry)
+		err = c.runAttempt(ctx)
+	}
+
+	return err
+}
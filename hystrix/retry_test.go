@@ -0,0 +1,139 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetry(t *testing.T) {
+	Convey("with a command which fails twice before succeeding", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{RetryMaxAttempts: 3, RetryBackoff: 1})
+
+		attempts := int32(0)
+		err := Do("", func() error {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				return fmt.Errorf("transient")
+			}
+			return nil
+		}, nil)
+
+		Convey("the run function is retried until it succeeds", func() {
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 3)
+
+			Convey("retries are counted separately from the final outcome", func() {
+				time.Sleep(10 * time.Millisecond)
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().Retries().Sum(time.Now()), ShouldEqual, 2)
+				So(cb.metrics.DefaultCollector().Successes().Sum(time.Now()), ShouldEqual, 1)
+				So(cb.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 0)
+				So(cb.metrics.DefaultCollector().NumRequests().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("with a command which keeps failing", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{RetryMaxAttempts: 3, RetryBackoff: 1})
+
+		attempts := int32(0)
+		err := Do("", func() error {
+			atomic.AddInt32(&attempts, 1)
+			return fmt.Errorf("broken")
+		}, nil)
+
+		Convey("the last error is returned after the maximum attempts", func() {
+			So(err.Error(), ShouldEqual, "broken")
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 3)
+
+			Convey("and a single failure is recorded", func() {
+				time.Sleep(10 * time.Millisecond)
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 1)
+				So(cb.metrics.DefaultCollector().Errors().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("with a command which fails with an error that is not retryable", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{RetryMaxAttempts: 3, RetryBackoff: 1})
+		getSettings("").Retry.Retryable = func(err error) bool {
+			return err.Error() != "permanent"
+		}
+
+		attempts := int32(0)
+		err := Do("", func() error {
+			atomic.AddInt32(&attempts, 1)
+			return fmt.Errorf("permanent")
+		}, nil)
+
+		Convey("the run function is not retried", func() {
+			So(err.Error(), ShouldEqual, "permanent")
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+		})
+	})
+
+	Convey("with a command whose backoff is longer than its timeout", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{Timeout: 20, RetryMaxAttempts: 3, RetryBackoff: 500})
+
+		attempts := int32(0)
+		err := Do("", func() error {
+			atomic.AddInt32(&attempts, 1)
+			return fmt.Errorf("transient")
+		}, nil)
+
+		Convey("the timeout error is returned without further attempts", func() {
+			So(err, ShouldResemble, ErrTimeout)
+			time.Sleep(50 * time.Millisecond)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+		})
+	})
+
+	Convey("with a command which times out during an attempt and retries without backoff", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{RetryMaxAttempts: 3})
+		cb, _, _ := GetCircuit("")
+
+		attempts := 0
+		cmd := &command{circuit: cb, timeoutChan: make(chan struct{})}
+		cmd.run = func(ctx context.Context) error {
+			attempts++
+			close(cmd.timeoutChan)
+			return fmt.Errorf("transient")
+		}
+		err := cmd.runWithRetry(context.Background())
+
+		Convey("no further attempt is made", func() {
+			So(err.Error(), ShouldEqual, "transient")
+			So(attempts, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	Convey("given a retry policy with a 10ms initial backoff capped at 50ms", t, func() {
+		policy := &RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+		Convey("the backoff grows exponentially up to the cap", func() {
+			So(policy.backoff(0), ShouldEqual, 10*time.Millisecond)
+			So(policy.backoff(1), ShouldEqual, 20*time.Millisecond)
+			So(policy.backoff(2), ShouldEqual, 40*time.Millisecond)
+			So(policy.backoff(3), ShouldEqual, 50*time.Millisecond)
+		})
+
+		Convey("and with jitter, the backoff is never longer than without", func() {
+			policy.Jitter = 0.5
+			for i := 0; i < 100; i++ {
+				So(policy.backoff(1), ShouldBeBetweenOrEqual, 10*time.Millisecond, 20*time.Millisecond)
+			}
+		})
+	})
+}
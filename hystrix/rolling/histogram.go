@@ -0,0 +1,141 @@
+package rolling
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultSignificantDigits is the precision with which a Timing records durations when none is given.
+const DefaultSignificantDigits = 3
+
+// maxTrackable is the longest duration a histogram tells apart, in microseconds. Longer ones are recorded as it.
+const maxTrackable = int64(time.Hour / time.Microsecond)
+
+// histogram counts durations in microseconds, like an HDR histogram. Durations are recorded into buckets
+// whose width doubles every time the durations double, so that each one keeps the given number of
+// significant digits while the number of buckets stays bounded. Only buckets which hold durations are stored.
+type histogram struct {
+	subBuckets int64
+	half       int64
+
+	counts map[int64]uint64
+	count  uint64
+	sum    int64
+}
+
+func newHistogram(significantDigits int) *histogram {
+	if significantDigits < 1 {
+		significantDigits = 1
+	}
+	if significantDigits > 5 {
+		significantDigits = 5
+	}
+
+	// the sub buckets of each bucket must resolve one unit in the last significant digit
+	subBuckets := int64(1) << uint(math.Ceil(math.Log2(2*math.Pow10(significantDigits))))
+	return &histogram{
+		subBuckets: subBuckets,
+		half:       subBuckets / 2,
+		counts:     make(map[int64]uint64),
+	}
+}
+
+// index returns the bucket counting the value.
+func (h *histogram) index(value int64) int64 {
+	shift := uint(0)
+	for value >= h.subBuckets {
+		value >>= 1
+		shift++
+	}
+	if shift == 0 {
+		return value
+	}
+	return int64(shift)*h.half + value
+}
+
+// highestValue returns the highest value counted by the bucket.
+func (h *histogram) highestValue(index int64) int64 {
+	if index < h.subBuckets {
+		return index
+	}
+	shift := uint(index/h.half - 1)
+	value := index - int64(shift)*h.half
+	return (value+1)<<shift - 1
+}
+
+func (h *histogram) record(d time.Duration) {
+	value := int64(d / time.Microsecond)
+	if value < 0 {
+		value = 0
+	}
+	if value > maxTrackable {
+		value = maxTrackable
+	}
+
+	h.counts[h.index(value)]++
+	h.count++
+	h.sum += value
+}
+
+func (h *histogram) merge(other *histogram) {
+	for index, count := range other.counts {
+		h.counts[index] += count
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+func (h *histogram) mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum/int64(h.count)) * time.Microsecond
+}
+
+// percentile returns the highest duration counted alongside the one at the given ordinal, starting at 1.
+func (h *histogram) percentile(ordinal int64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	indexes := make([]int64, 0, len(h.counts))
+	for index := range h.counts {
+		indexes = append(indexes, index)
+	}
+	sort.Sort(byIndex(indexes))
+
+	var seen uint64
+	for _, index := range indexes {
+		seen += h.counts[index]
+		if int64(seen) >= ordinal {
+			return time.Duration(h.highestValue(index)) * time.Microsecond
+		}
+	}
+	return time.Duration(h.highestValue(indexes[len(indexes)-1])) * time.Microsecond
+}
+
+// durations returns every counted duration from shortest to longest, each one as the highest duration
+// counted alongside it.
+func (h *histogram) durations() []time.Duration {
+	indexes := make([]int64, 0, len(h.counts))
+	for index := range h.counts {
+		indexes = append(indexes, index)
+	}
+	sort.Sort(byIndex(indexes))
+
+	durations := make([]time.Duration, 0, h.count)
+	for _, index := range indexes {
+		d := time.Duration(h.highestValue(index)) * time.Microsecond
+		for i := uint64(0); i < h.counts[index]; i++ {
+			durations = append(durations, d)
+		}
+	}
+	return durations
+}
+
+type byIndex []int64
+
+func (c byIndex) Len() int           { return len(c) }
+func (c byIndex) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byIndex) Less(i, j int) bool { return c[i] < c[j] }
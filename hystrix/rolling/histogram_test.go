@@ -0,0 +1,53 @@
+package rolling
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHistogram(t *testing.T) {
+	Convey("given a histogram with 3 significant digits", t, func() {
+		h := newHistogram(3)
+
+		Convey("short durations are recorded exactly", func() {
+			h.record(1500 * time.Microsecond)
+			So(h.percentile(1), ShouldEqual, 1500*time.Microsecond)
+		})
+
+		Convey("long durations are recorded within 0.1%", func() {
+			h.record(1234567 * time.Microsecond)
+			So(h.percentile(1), ShouldBeBetweenOrEqual, 1234567*time.Microsecond, 1235802*time.Microsecond)
+		})
+
+		Convey("durations longer than an hour are recorded as an hour", func() {
+			h.record(2 * time.Hour)
+			So(h.percentile(1), ShouldBeBetweenOrEqual, time.Hour, time.Hour+4*time.Second)
+		})
+
+		Convey("the number of buckets stays bounded however many durations are recorded", func() {
+			for d := time.Duration(0); d < time.Second; d += 7 * time.Microsecond {
+				h.record(d)
+			}
+			So(len(h.counts), ShouldBeLessThan, 20000)
+			So(h.count, ShouldEqual, 142858)
+		})
+
+		Convey("the mean is calculated from the exact durations", func() {
+			h.record(1 * time.Millisecond)
+			h.record(2 * time.Millisecond)
+			So(h.mean(), ShouldEqual, 1500*time.Microsecond)
+		})
+
+		Convey("merging adds up the durations of both", func() {
+			other := newHistogram(3)
+			other.record(3 * time.Millisecond)
+			h.record(1 * time.Millisecond)
+			h.merge(other)
+
+			So(h.count, ShouldEqual, 2)
+			So(h.percentile(2), ShouldBeBetweenOrEqual, 3*time.Millisecond, 3003*time.Microsecond)
+		})
+	})
+}
@@ -1,113 +1,153 @@
 package rolling
 
 import (
-	"sync"
+	"math"
+	"runtime"
+	"sync/atomic"
 	"time"
 )
 
+// rotating marks a bucket which is being reset for a new time bucket.
+const rotating = -1
+
 // Number tracks a numberBucket over a bounded number of
-// time buckets. Currently the buckets are one second long and only the last 10 seconds are kept.
+// time buckets. By default the buckets are one second long and only the last 10 seconds are kept.
+//
+// The buckets form a ring which is updated with atomic operations only. A bucket is lazily reset
+// by the first update which finds it still holding an expired time bucket. It no longer exposes its
+// Buckets and Mutex, the values are read through Sum, Max and Avg.
 type Number struct {
-	Buckets map[int64]*numberBucket
-	Mutex   *sync.RWMutex
+	buckets []numberBucket
+
+	window     time.Duration
+	bucketSize int64
+	numBuckets int64
 }
 
 type numberBucket struct {
-	Value float64
+	// time bucket the value belongs to, or rotating
+	index int64
+	// bits of the float64 value
+	value uint64
 }
 
 // NewNumber initializes a RollingNumber struct.
 func NewNumber() *Number {
+	return NewNumberWindow(10*time.Second, 10)
+}
+
+// NewNumberWindow initializes a RollingNumber struct keeping the given window, split into the given number of buckets.
+func NewNumberWindow(window time.Duration, buckets int) *Number {
+	bucketSize, numBuckets := bucketsOf(window, buckets)
 	r := &Number{
-		Buckets: make(map[int64]*numberBucket),
-		Mutex:   &sync.RWMutex{},
+		buckets:    make([]numberBucket, numBuckets),
+		window:     window,
+		bucketSize: bucketSize,
+		numBuckets: numBuckets,
 	}
 	return r
 }
 
-func (r *Number) getCurrentBucket() *numberBucket {
-	now := time.Now().Unix()
-	var bucket *numberBucket
-	var ok bool
-
-	if bucket, ok = r.Buckets[now]; !ok {
-		bucket = &numberBucket{}
-		r.Buckets[now] = bucket
+// bucketsOf returns the length of each bucket in nanoseconds and their number, splitting the window as evenly as possible.
+func bucketsOf(window time.Duration, buckets int) (int64, int64) {
+	if buckets < 1 {
+		buckets = 1
+	}
+	bucketSize := int64(window) / int64(buckets)
+	if bucketSize < 1 {
+		bucketSize = 1
 	}
+	return bucketSize, int64(buckets)
+}
 
-	return bucket
+func (r *Number) bucketOf(t time.Time) int64 {
+	return t.UnixNano() / r.bucketSize
 }
 
-func (r *Number) removeOldBuckets() {
-	now := time.Now().Unix() - 10
+// Window returns how long values are kept for.
+func (r *Number) Window() time.Duration {
+	return r.window
+}
 
-	for timestamp := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp <= now {
-			delete(r.Buckets, timestamp)
+// update applies the function to the value of the current time bucket, until it reports
+// that the value it returned was stored.
+func (r *Number) update(f func(b *numberBucket) bool) {
+	index := r.bucketOf(time.Now())
+	b := &r.buckets[index%r.numBuckets]
+
+	for {
+		current := atomic.LoadInt64(&b.index)
+		switch {
+		case current == index:
+			if f(b) {
+				return
+			}
+		case current == rotating:
+			runtime.Gosched()
+		case current > index:
+			// the ring has moved on while this update was delayed, so the value is already out of the window
+			return
+		case atomic.CompareAndSwapInt64(&b.index, current, rotating):
+			atomic.StoreUint64(&b.value, 0)
+			atomic.StoreInt64(&b.index, index)
 		}
 	}
 }
 
 // Increment increments the number in current timeBucket.
 func (r *Number) Increment(i float64) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
-
-	b := r.getCurrentBucket()
-	b.Value += i
-	r.removeOldBuckets()
+	r.update(func(b *numberBucket) bool {
+		old := atomic.LoadUint64(&b.value)
+		return atomic.CompareAndSwapUint64(&b.value, old, math.Float64bits(math.Float64frombits(old)+i))
+	})
 }
 
 // UpdateMax updates the maximum value in the current bucket.
 func (r *Number) UpdateMax(n float64) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
+	r.update(func(b *numberBucket) bool {
+		old := atomic.LoadUint64(&b.value)
+		if n <= math.Float64frombits(old) {
+			return true
+		}
+		return atomic.CompareAndSwapUint64(&b.value, old, math.Float64bits(n))
+	})
+}
 
-	b := r.getCurrentBucket()
-	if n > b.Value {
-		b.Value = n
+// values calls the function with the value of every bucket within the window ending at now.
+func (r *Number) values(now time.Time, f func(value float64)) {
+	newest := r.bucketOf(now)
+	for i := range r.buckets {
+		b := &r.buckets[i]
+		index := atomic.LoadInt64(&b.index)
+		if index > newest-r.numBuckets && index <= newest {
+			f(math.Float64frombits(atomic.LoadUint64(&b.value)))
+		}
 	}
-	r.removeOldBuckets()
 }
 
-// Sum sums the values over the buckets in the last 10 seconds.
+// Sum sums the values over the buckets in the window.
 func (r *Number) Sum(now time.Time) float64 {
 	sum := float64(0)
-
-	r.Mutex.RLock()
-	defer r.Mutex.RUnlock()
-
-	for timestamp, bucket := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp >= now.Unix()-10 {
-			sum += bucket.Value
-		}
-	}
+	r.values(now, func(value float64) {
+		sum += value
+	})
 
 	return sum
 }
 
-// Max returns the maximum value seen in the last 10 seconds.
+// Max returns the maximum value seen in the window.
 func (r *Number) Max(now time.Time) float64 {
 	var max float64
-
-	r.Mutex.RLock()
-	defer r.Mutex.RUnlock()
-
-	for timestamp, bucket := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp >= now.Unix()-10 {
-			if bucket.Value > max {
-				max = bucket.Value
-			}
+	r.values(now, func(value float64) {
+		if value > max {
+			max = value
 		}
-	}
+	})
 
 	return max
 }
 
-// Avg return the average value seen in the last 10 seconds.
+// Avg return the average value per second seen in the window.
 func (r *Number) Avg(now time.Time) float64 {
-	return r.Sum(now) / 10
+	return r.Sum(now) / r.window.Seconds()
 }
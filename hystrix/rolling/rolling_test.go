@@ -1,6 +1,7 @@
 package rolling
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -36,6 +37,50 @@ func TestAvg(t *testing.T) {
 	})
 }
 
+func TestNumberWindow(t *testing.T) {
+	Convey("when adding values to a rolling number with a 200ms window", t, func() {
+		n := NewNumberWindow(200*time.Millisecond, 4)
+		n.Increment(1)
+		n.Increment(2)
+
+		Convey("it should sum them within the window", func() {
+			So(n.Sum(time.Now()), ShouldEqual, 3)
+			So(n.Window(), ShouldEqual, 200*time.Millisecond)
+		})
+
+		Convey("it should average them per second over the window", func() {
+			So(n.Avg(time.Now()), ShouldEqual, 15)
+		})
+
+		Convey("it should forget them once the window has passed", func() {
+			time.Sleep(250 * time.Millisecond)
+			So(n.Sum(time.Now()), ShouldEqual, 0)
+			So(n.Max(time.Now()), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestConcurrentIncrement(t *testing.T) {
+	Convey("when incrementing a rolling number from many goroutines", t, func() {
+		n := NewNumber()
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					n.Increment(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		Convey("no increment should be lost", func() {
+			So(n.Sum(time.Now()), ShouldEqual, 10000)
+		})
+	})
+}
+
 func BenchmarkRollingNumberIncrement(b *testing.B) {
 	n := NewNumber()
 
@@ -55,3 +100,40 @@ func BenchmarkRollingNumberUpdateMax(b *testing.B) {
 		n.UpdateMax(float64(i))
 	}
 }
+
+func BenchmarkRollingNumberIncrementParallel(b *testing.B) {
+	n := NewNumber()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n.Increment(1)
+		}
+	})
+}
+
+func BenchmarkRollingNumberUpdateMaxParallel(b *testing.B) {
+	n := NewNumber()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			n.UpdateMax(float64(i))
+			i++
+		}
+	})
+}
+
+func BenchmarkRollingNumberSum(b *testing.B) {
+	n := NewNumber()
+	n.Increment(1)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		n.Sum(time.Now())
+	}
+}
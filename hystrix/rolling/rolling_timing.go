@@ -2,125 +2,181 @@ package rolling
 
 import (
 	"math"
-	"sort"
 	"sync"
 	"time"
 )
 
-// Timing maintains time Durations for each time bucket.
-// The Durations are kept in an array to allow for a variety of
-// statistics to be calculated from the source data.
+// Timing maintains a histogram of time Durations for each time bucket.
+// The histograms are merged to calculate statistics over the window,
+// using a bounded amount of memory however many Durations are added.
 type Timing struct {
-	Buckets map[int64]*timingBucket
+	// Buckets holds the durations of each second of the window, as of the last call to SortedDurations.
+	//
+	// Deprecated: Buckets is only filled in by SortedDurations. Use PercentileDuration and MeanDuration instead.
+	Buckets map[int64]*durationsBucket
 	Mutex   *sync.RWMutex
 
+	// CachedSortedDurations and LastCachedTime are the result of the last call to SortedDurations and when it was made.
+	//
+	// Deprecated: they are only filled in by SortedDurations. Use PercentileDuration and MeanDuration instead.
 	CachedSortedDurations []time.Duration
 	LastCachedTime        int64
+
+	buckets           []timingBucket
+	significantDigits int
+	bucketSize        int64
+	numBuckets        int64
+
+	cached     *histogram
+	cachedTime int64
 }
 
 type timingBucket struct {
+	index     int64
+	histogram *histogram
+}
+
+// durationsBucket holds the durations of a second, as returned by SortedDurations.
+type durationsBucket struct {
 	Durations []time.Duration
 }
 
 // NewTiming creates a RollingTiming struct.
 func NewTiming() *Timing {
+	return NewTimingWindow(60*time.Second, 60)
+}
+
+// NewTimingWindow creates a RollingTiming struct keeping the given window, split into the given number of buckets.
+func NewTimingWindow(window time.Duration, buckets int) *Timing {
+	return NewTimingWindowWithDigits(window, buckets, DefaultSignificantDigits)
+}
+
+// NewTimingWindowWithDigits creates a RollingTiming struct like NewTimingWindow, recording durations with the given
+// number of significant digits, between 1 and 5. More digits use more memory.
+func NewTimingWindowWithDigits(window time.Duration, buckets int, significantDigits int) *Timing {
+	bucketSize, numBuckets := bucketsOf(window, buckets)
 	r := &Timing{
-		Buckets: make(map[int64]*timingBucket),
-		Mutex:   &sync.RWMutex{},
+		Buckets:           make(map[int64]*durationsBucket),
+		Mutex:             &sync.RWMutex{},
+		buckets:           make([]timingBucket, numBuckets),
+		significantDigits: significantDigits,
+		bucketSize:        bucketSize,
+		numBuckets:        numBuckets,
 	}
 	return r
 }
 
-type byDuration []time.Duration
+func (r *Timing) bucketOf(t time.Time) int64 {
+	return t.UnixNano() / r.bucketSize
+}
 
-func (c byDuration) Len() int           { return len(c) }
-func (c byDuration) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
-func (c byDuration) Less(i, j int) bool { return c[i] < c[j] }
+// snapshot returns the histogram of the durations in the window. It is kept for up to a second,
+// or a bucket when they are shorter.
+func (r *Timing) snapshot() *histogram {
+	now := time.Now()
+	maxAge := r.bucketSize
+	if maxAge > time.Second.Nanoseconds() {
+		maxAge = time.Second.Nanoseconds()
+	}
 
-// SortedDurations returns an array of time.Duration sorted from shortest
-// to longest that have occurred in the last 60 seconds.
-func (r *Timing) SortedDurations() []time.Duration {
 	r.Mutex.RLock()
-	t := r.LastCachedTime
+	cached, t := r.cached, r.cachedTime
 	r.Mutex.RUnlock()
 
-	if t+time.Second.Nanoseconds() > time.Now().UnixNano() {
+	if cached != nil && t+maxAge > now.UnixNano() {
 		// don't recalculate if current cache is still fresh
-		return r.CachedSortedDurations
+		return cached
 	}
 
-	var durations byDuration
-	now := time.Now()
+	h := newHistogram(r.significantDigits)
+	newest := r.bucketOf(now)
 
 	r.Mutex.Lock()
 	defer r.Mutex.Unlock()
 
-	for timestamp, b := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp >= now.Unix()-60 {
-			for _, d := range b.Durations {
-				durations = append(durations, d)
-			}
+	for _, b := range r.buckets {
+		if b.histogram != nil && b.index > newest-r.numBuckets && b.index <= newest {
+			h.merge(b.histogram)
 		}
 	}
 
-	sort.Sort(durations)
-
-	r.CachedSortedDurations = durations
-	r.LastCachedTime = time.Now().UnixNano()
+	r.cached = h
+	r.cachedTime = now.UnixNano()
 
-	return r.CachedSortedDurations
+	return h
 }
 
-func (r *Timing) getCurrentBucket() *timingBucket {
+// SortedDurations returns an array of time.Duration sorted from shortest to longest that have occurred in the
+// window. Durations are rounded up to the precision of the histogram, and the result is kept for a second.
+//
+// Deprecated: SortedDurations allocates every duration of the window again. Use PercentileDuration and
+// MeanDuration instead.
+func (r *Timing) SortedDurations() []time.Duration {
 	r.Mutex.RLock()
-	now := time.Now()
-	bucket, exists := r.Buckets[now.Unix()]
+	t, cached := r.LastCachedTime, r.CachedSortedDurations
 	r.Mutex.RUnlock()
 
-	if !exists {
-		r.Mutex.Lock()
-		defer r.Mutex.Unlock()
-
-		r.Buckets[now.Unix()] = &timingBucket{}
-		bucket = r.Buckets[now.Unix()]
+	if t+time.Second.Nanoseconds() > time.Now().UnixNano() {
+		// don't recalculate if current cache is still fresh
+		return cached
 	}
 
-	return bucket
-}
-
-func (r *Timing) removeOldBuckets() {
 	now := time.Now()
+	newest := r.bucketOf(now)
+	h := newHistogram(r.significantDigits)
+	buckets := make(map[int64]*durationsBucket)
+
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	for _, b := range r.buckets {
+		if b.histogram == nil || b.index <= newest-r.numBuckets || b.index > newest {
+			continue
+		}
+		h.merge(b.histogram)
 
-	for timestamp := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp <= now.Unix()-60 {
-			delete(r.Buckets, timestamp)
+		second := b.index * r.bucketSize / time.Second.Nanoseconds()
+		if buckets[second] == nil {
+			buckets[second] = &durationsBucket{}
 		}
+		buckets[second].Durations = append(buckets[second].Durations, b.histogram.durations()...)
 	}
+
+	r.Buckets = buckets
+	r.CachedSortedDurations = h.durations()
+	r.LastCachedTime = now.UnixNano()
+
+	return r.CachedSortedDurations
 }
 
-// Add appends the time.Duration given to the current time bucket.
+// Add records the time.Duration given in the current time bucket.
 func (r *Timing) Add(duration time.Duration) {
-	b := r.getCurrentBucket()
+	index := r.bucketOf(time.Now())
 
 	r.Mutex.Lock()
 	defer r.Mutex.Unlock()
 
-	b.Durations = append(b.Durations, duration)
-	r.removeOldBuckets()
+	b := &r.buckets[index%r.numBuckets]
+	if b.histogram == nil || b.index != index {
+		b.index = index
+		b.histogram = newHistogram(r.significantDigits)
+	}
+	b.histogram.record(duration)
 }
 
-// Percentile computes the percentile given with a linear interpolation.
+// Percentile computes the percentile given in milliseconds.
 func (r *Timing) Percentile(p float64) uint32 {
-	sortedDurations := r.SortedDurations()
-	length := len(sortedDurations)
-	if length <= 0 {
+	return uint32(r.PercentileDuration(p) / time.Millisecond)
+}
+
+// PercentileDuration computes the percentile given with microsecond resolution.
+func (r *Timing) PercentileDuration(p float64) time.Duration {
+	h := r.snapshot()
+	if h.count == 0 {
 		return 0
 	}
 
-	pos := r.ordinal(len(sortedDurations), p) - 1
-	return uint32(sortedDurations[pos].Nanoseconds() / 1000000)
+	return h.percentile(r.ordinal(int(h.count), p))
 }
 
 func (r *Timing) ordinal(length int, percentile float64) int64 {
@@ -131,18 +187,12 @@ func (r *Timing) ordinal(length int, percentile float64) int64 {
 	return int64(math.Ceil((percentile / float64(100)) * float64(length)))
 }
 
-// Mean computes the average timing in the last 60 seconds.
+// Mean computes the average timing in the window in milliseconds.
 func (r *Timing) Mean() uint32 {
-	sortedDurations := r.SortedDurations()
-	var sum time.Duration
-	for _, d := range sortedDurations {
-		sum += d
-	}
-
-	length := int64(len(sortedDurations))
-	if length == 0 {
-		return 0
-	}
+	return uint32(r.snapshot().mean() / time.Millisecond)
+}
 
-	return uint32(sum.Nanoseconds()/length) / 1000000
+// MeanDuration computes the average timing in the window with microsecond resolution.
+func (r *Timing) MeanDuration() time.Duration {
+	return r.snapshot().mean()
 }
@@ -65,6 +65,52 @@ func TestOrdinal(t *testing.T) {
 				So(r.Percentile(99), ShouldEqual, 1015)
 				So(r.Percentile(100), ShouldEqual, 1015)
 			})
+
+			Convey("calculates percentiles with microsecond resolution", func() {
+				So(r.PercentileDuration(0), ShouldEqual, time.Millisecond)
+				So(r.PercentileDuration(100), ShouldBeBetweenOrEqual, 1015*time.Millisecond, 1016*time.Millisecond)
+			})
+
+			Convey("still returns the sorted durations and fills in the deprecated fields", func() {
+				sorted := r.SortedDurations()
+				So(len(sorted), ShouldEqual, len(durations))
+				So(sorted[0], ShouldEqual, time.Millisecond)
+				So(sorted[len(sorted)-1], ShouldBeBetweenOrEqual, 1015*time.Millisecond, 1016*time.Millisecond)
+				So(r.CachedSortedDurations, ShouldResemble, sorted)
+				So(r.LastCachedTime, ShouldBeGreaterThan, 0)
+
+				var bucketed int
+				for _, b := range r.Buckets {
+					bucketed += len(b.Durations)
+				}
+				So(bucketed, ShouldEqual, len(durations))
+			})
+		})
+	})
+}
+
+func TestTimingWindow(t *testing.T) {
+	Convey("given a rolling timing with a 200ms window", t, func() {
+		r := NewTimingWindow(200*time.Millisecond, 4)
+		r.Add(100 * time.Millisecond)
+
+		Convey("the timing should be kept within the window", func() {
+			So(r.Mean(), ShouldEqual, 100)
+		})
+
+		Convey("the timing should be forgotten once the window has passed", func() {
+			time.Sleep(250 * time.Millisecond)
+			So(r.Mean(), ShouldEqual, 0)
 		})
 	})
 }
+
+func BenchmarkRollingTimingAdd(b *testing.B) {
+	r := NewTiming()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r.Add(time.Duration(i) * time.Microsecond)
+	}
+}
@@ -18,6 +18,22 @@ var (
 	DefaultErrorPercentThreshold = 50
 	// DefaultQueueSizeRejectionThreshold reject requests when the queue size exceeds the given limit
 	DefaultQueueSizeRejectionThreshold = DefaultMaxConcurrent * 5
+	// DefaultRequestCacheEnabled shares the responses of commands with the same cache key within a request cache
+	DefaultRequestCacheEnabled = true
+	// DefaultRequestLogEnabled records commands in the request log carried by their context
+	DefaultRequestLogEnabled = true
+	// DefaultMetricsRollingStatisticalWindow is how long, in milliseconds, counters are kept for health and dashboard metrics
+	DefaultMetricsRollingStatisticalWindow = 10000
+	// DefaultMetricsRollingBuckets is how many buckets the statistical window is split into
+	DefaultMetricsRollingBuckets = 10
+	// DefaultMetricsRollingPercentileWindow is how long, in milliseconds, durations are kept for percentiles
+	DefaultMetricsRollingPercentileWindow = 60000
+	// DefaultMetricsRollingPercentileBuckets is how many buckets the percentile window is split into
+	DefaultMetricsRollingPercentileBuckets = 60
+	// DefaultMetricsStateInterval is how often, in milliseconds, the state of a circuit and its pool is sent to metric collectors
+	DefaultMetricsStateInterval = 1000
+	// DefaultFallbackTimeout is how long, in milliseconds, to wait for a fallback to complete. 0 waits forever
+	DefaultFallbackTimeout = 0
 )
 
 // Settings Setting for the hystrixCommand
@@ -30,6 +46,28 @@ type Settings struct {
 	SleepWindow                 time.Duration
 	ErrorPercentThreshold       int
 	QueueSizeRejectionThreshold int
+	FallbackTimeout             time.Duration
+	Retry                       *RetryPolicy
+	Hedge                       *HedgePolicy
+	RequestCacheEnabled         bool
+	RequestLogEnabled           bool
+	Ramp                        *RampPolicy
+	SleepWindowBackoff          *SleepWindowBackoff
+	// RateLimit is the number of executions per second allowed across commands sharing the RateLimitKey. 0 disables it
+	RateLimit float64
+	// RateLimitBurst is how many executions may happen at once, defaulting to one second worth of the RateLimit
+	RateLimitBurst int
+	// RateLimitKey shares a rate limit between commands, defaulting to the command name
+	RateLimitKey string
+
+	// the rolling windows are applied when the circuit is created
+	MetricsRollingStatisticalWindow time.Duration
+	MetricsRollingBuckets           int
+	MetricsRollingPercentileWindow  time.Duration
+	MetricsRollingPercentileBuckets int
+	// MetricsStateInterval is how often the state of the circuit is sent to metric collectors, besides when it
+	// opens or closes. 0 disables the periodic updates
+	MetricsStateInterval time.Duration
 }
 
 // CommandConfig is used to tune circuit settings at runtime
@@ -43,6 +81,37 @@ type CommandConfig struct {
 	ErrorPercentThreshold  int    `json:"error_percent_threshold"`
 	// for more details refer - https://github.com/Netflix/Hystrix/wiki/Configuration#maxqueuesize
 	QueueSizeRejectionThreshold int `json:"queue_size_rejection_threshold"`
+	FallbackTimeout             int `json:"fallback_timeout"`
+	// RetryMaxAttempts enables retries with an exponential backoff starting at RetryBackoff when greater than 1
+	RetryMaxAttempts int `json:"retry_max_attempts"`
+	RetryBackoff     int `json:"retry_backoff"`
+	// HedgeMaxAttempts enables hedging of slow attempts, every HedgeDelay or the rolling p95 when it is 0, when greater than 1
+	HedgeMaxAttempts int `json:"hedge_max_attempts"`
+	HedgeDelay       int `json:"hedge_delay"`
+	// RequestCacheEnabled defaults to DefaultRequestCacheEnabled when not set
+	RequestCacheEnabled *bool `json:"request_cache_enabled"`
+	// RequestLogEnabled defaults to DefaultRequestLogEnabled when not set
+	RequestLogEnabled *bool `json:"request_log_enabled"`
+	// MetricsRollingStatisticalWindow is split into MetricsRollingBuckets for counters, and MetricsRollingPercentileWindow
+	// into MetricsRollingPercentileBuckets for durations. They apply to circuits created afterwards
+	MetricsRollingStatisticalWindow int `json:"metrics_rolling_statistical_window"`
+	MetricsRollingBuckets           int `json:"metrics_rolling_buckets"`
+	MetricsRollingPercentileWindow  int `json:"metrics_rolling_percentile_window"`
+	MetricsRollingPercentileBuckets int `json:"metrics_rolling_percentile_buckets"`
+	// MetricsStateInterval is how often the state of the circuit is sent to metric collectors, a negative interval disabling
+	// the periodic updates. It applies to circuits created afterwards
+	MetricsStateInterval int `json:"metrics_state_interval"`
+	// MaxSleepWindow enables growing the sleep window by SleepWindowMultiplier, or DefaultSleepWindowMultiplier when it is 0, after every failed probe
+	MaxSleepWindow        int     `json:"max_sleep_window"`
+	SleepWindowMultiplier float64 `json:"sleep_window_multiplier"`
+	// RampDuration enables a gradual ramp up of traffic after the circuit closes, starting at RampInitialPercent
+	RampDuration       int  `json:"ramp_duration"`
+	RampInitialPercent int  `json:"ramp_initial_percent"`
+	RampExponential    bool `json:"ramp_exponential"`
+	// RateLimit is the number of executions per second allowed, such as 0.5 for one every 2 seconds, 0 disables rate limiting
+	RateLimit      float64 `json:"rate_limit"`
+	RateLimitBurst int     `json:"rate_limit_burst"`
+	RateLimitKey   string  `json:"rate_limit_key"`
 }
 
 var circuitSettings map[string]*Settings
@@ -103,6 +172,81 @@ func ConfigureCommand(name string, config CommandConfig) {
 		queueSizeRejectionThreshold = config.QueueSizeRejectionThreshold
 	}
 
+	fallbackTimeout := DefaultFallbackTimeout
+	if config.FallbackTimeout != 0 {
+		fallbackTimeout = config.FallbackTimeout
+	}
+
+	var retry *RetryPolicy
+	if config.RetryMaxAttempts > 1 {
+		retry = &RetryPolicy{
+			MaxAttempts:    config.RetryMaxAttempts,
+			InitialBackoff: time.Duration(config.RetryBackoff) * time.Millisecond,
+		}
+	}
+
+	var hedge *HedgePolicy
+	if config.HedgeMaxAttempts > 1 {
+		hedge = &HedgePolicy{
+			MaxAttempts: config.HedgeMaxAttempts,
+			Delay:       time.Duration(config.HedgeDelay) * time.Millisecond,
+		}
+	}
+
+	statisticalWindow := DefaultMetricsRollingStatisticalWindow
+	if config.MetricsRollingStatisticalWindow != 0 {
+		statisticalWindow = config.MetricsRollingStatisticalWindow
+	}
+
+	statisticalBuckets := DefaultMetricsRollingBuckets
+	if config.MetricsRollingBuckets != 0 {
+		statisticalBuckets = config.MetricsRollingBuckets
+	}
+
+	percentileWindow := DefaultMetricsRollingPercentileWindow
+	if config.MetricsRollingPercentileWindow != 0 {
+		percentileWindow = config.MetricsRollingPercentileWindow
+	}
+
+	percentileBuckets := DefaultMetricsRollingPercentileBuckets
+	if config.MetricsRollingPercentileBuckets != 0 {
+		percentileBuckets = config.MetricsRollingPercentileBuckets
+	}
+
+	stateInterval := DefaultMetricsStateInterval
+	if config.MetricsStateInterval > 0 {
+		stateInterval = config.MetricsStateInterval
+	} else if config.MetricsStateInterval < 0 {
+		stateInterval = 0
+	}
+
+	var sleepWindowBackoff *SleepWindowBackoff
+	if config.MaxSleepWindow > sleep {
+		sleepWindowBackoff = &SleepWindowBackoff{
+			Multiplier:     config.SleepWindowMultiplier,
+			MaxSleepWindow: time.Duration(config.MaxSleepWindow) * time.Millisecond,
+		}
+	}
+
+	var ramp *RampPolicy
+	if config.RampDuration > 0 {
+		ramp = &RampPolicy{
+			Duration:       time.Duration(config.RampDuration) * time.Millisecond,
+			InitialPercent: float64(config.RampInitialPercent),
+			Exponential:    config.RampExponential,
+		}
+	}
+
+	requestCacheEnabled := DefaultRequestCacheEnabled
+	if config.RequestCacheEnabled != nil {
+		requestCacheEnabled = *config.RequestCacheEnabled
+	}
+
+	requestLogEnabled := DefaultRequestLogEnabled
+	if config.RequestLogEnabled != nil {
+		requestLogEnabled = *config.RequestLogEnabled
+	}
+
 	groupName := name
 	if config.CommandGroup != "" {
 		groupName = config.CommandGroup
@@ -117,9 +261,48 @@ func ConfigureCommand(name string, config CommandConfig) {
 		SleepWindow:                 time.Duration(sleep) * time.Millisecond,
 		ErrorPercentThreshold:       errorPercent,
 		QueueSizeRejectionThreshold: queueSizeRejectionThreshold,
+		FallbackTimeout:             time.Duration(fallbackTimeout) * time.Millisecond,
+		Retry:                       retry,
+		Hedge:                       hedge,
+		RequestCacheEnabled:         requestCacheEnabled,
+		RequestLogEnabled:           requestLogEnabled,
+		Ramp:                        ramp,
+		SleepWindowBackoff:          sleepWindowBackoff,
+		RateLimit:                   config.RateLimit,
+		RateLimitBurst:              config.RateLimitBurst,
+		RateLimitKey:                config.RateLimitKey,
+
+		MetricsRollingStatisticalWindow: time.Duration(statisticalWindow) * time.Millisecond,
+		MetricsRollingBuckets:           statisticalBuckets,
+		MetricsRollingPercentileWindow:  time.Duration(percentileWindow) * time.Millisecond,
+		MetricsRollingPercentileBuckets: percentileBuckets,
+		MetricsStateInterval:            time.Duration(stateInterval) * time.Millisecond,
 	})
 }
 
+// clone returns a copy of the settings which shares none of their policies, so that changing it cannot
+// change the settings of a circuit.
+func (s *Settings) clone() Settings {
+	c := *s
+	if s.Retry != nil {
+		retry := *s.Retry
+		c.Retry = &retry
+	}
+	if s.Hedge != nil {
+		hedge := *s.Hedge
+		c.Hedge = &hedge
+	}
+	if s.Ramp != nil {
+		ramp := *s.Ramp
+		c.Ramp = &ramp
+	}
+	if s.SleepWindowBackoff != nil {
+		backoff := *s.SleepWindowBackoff
+		c.SleepWindowBackoff = &backoff
+	}
+	return c
+}
+
 func getSettings(name string) *Settings {
 	settingsMutex.RLock()
 	s, exists := circuitSettings[name]
@@ -57,6 +57,92 @@ func TestSleepWindowDefault(t *testing.T) {
 	})
 }
 
+func TestConfigureFallbackTimeout(t *testing.T) {
+	Convey("given a command configured with a 50 milliseconds fallback timeout", t, func() {
+		ConfigureCommand("", CommandConfig{FallbackTimeout: 50})
+
+		Convey("reading the fallback timeout should be the same", func() {
+			So(getSettings("").FallbackTimeout, ShouldEqual, time.Duration(50*time.Millisecond))
+		})
+	})
+
+	Convey("given default settings", t, func() {
+		ConfigureCommand("", CommandConfig{})
+
+		Convey("the fallback timeout should be disabled", func() {
+			So(getSettings("").FallbackTimeout, ShouldEqual, time.Duration(0))
+		})
+	})
+}
+
+func TestRequestCacheEnabledDefault(t *testing.T) {
+	Convey("given default settings", t, func() {
+		ConfigureCommand("", CommandConfig{})
+
+		Convey("the request cache should be enabled", func() {
+			So(getSettings("").RequestCacheEnabled, ShouldBeTrue)
+		})
+
+		Convey("the request log should be enabled", func() {
+			So(getSettings("").RequestLogEnabled, ShouldBeTrue)
+		})
+	})
+}
+
+func TestConfigureRamp(t *testing.T) {
+	Convey("given a ramp duration", t, func() {
+		ConfigureCommand("", CommandConfig{RampDuration: 30000, RampInitialPercent: 5})
+
+		Convey("a ramp policy should be configured", func() {
+			So(getSettings("").Ramp.Duration, ShouldEqual, 30*time.Second)
+			So(getSettings("").Ramp.InitialPercent, ShouldEqual, 5)
+			So(getSettings("").Ramp.Exponential, ShouldBeFalse)
+		})
+	})
+
+	Convey("given default settings", t, func() {
+		ConfigureCommand("", CommandConfig{})
+
+		Convey("no ramp policy should be configured", func() {
+			So(getSettings("").Ramp, ShouldBeNil)
+		})
+	})
+}
+
+func TestRollingWindowsDefault(t *testing.T) {
+	Convey("given default settings", t, func() {
+		ConfigureCommand("", CommandConfig{})
+
+		Convey("counters should be kept for 10 seconds in 10 buckets", func() {
+			So(getSettings("").MetricsRollingStatisticalWindow, ShouldEqual, 10*time.Second)
+			So(getSettings("").MetricsRollingBuckets, ShouldEqual, 10)
+		})
+
+		Convey("durations should be kept for 60 seconds in 60 buckets", func() {
+			So(getSettings("").MetricsRollingPercentileWindow, ShouldEqual, 60*time.Second)
+			So(getSettings("").MetricsRollingPercentileBuckets, ShouldEqual, 60)
+		})
+	})
+}
+
+func TestMetricsStateInterval(t *testing.T) {
+	Convey("given default settings", t, func() {
+		ConfigureCommand("", CommandConfig{})
+
+		Convey("the circuit state should be sent every second", func() {
+			So(getSettings("").MetricsStateInterval, ShouldEqual, time.Second)
+		})
+	})
+
+	Convey("given a negative interval", t, func() {
+		ConfigureCommand("", CommandConfig{MetricsStateInterval: -1})
+
+		Convey("the periodic updates should be disabled", func() {
+			So(getSettings("").MetricsStateInterval, ShouldEqual, 0)
+		})
+	})
+}
+
 func TestGetCircuitSettings(t *testing.T) {
 	Convey("when calling GetCircuitSettings", t, func() {
 		ConfigureCommand("test", CommandConfig{Timeout: 30000})
@@ -0,0 +1,70 @@
+package hystrix
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSleepWindowMultiplier is the factor applied to the sleep window after each failed probe when a
+// SleepWindowBackoff does not set one.
+const DefaultSleepWindowMultiplier = 2
+
+// SleepWindowBackoff describes how the sleep window of an open circuit grows while its half-open probes keep
+// failing, so that a dependency which stays down is probed less and less often. The sleep window returns to
+// SleepWindow once the circuit closes.
+type SleepWindowBackoff struct {
+	// Multiplier grows the sleep window after every failed probe. If 0, defaults to DefaultSleepWindowMultiplier.
+	Multiplier float64
+	// MaxSleepWindow caps the sleep window.
+	MaxSleepWindow time.Duration
+	// Jitter randomly shortens each grown sleep window by up to this fraction of it, between 0 and 1.
+	Jitter float64
+}
+
+// sleepWindow returns the sleep window after the given number of consecutive failed probes.
+func (b *SleepWindowBackoff) sleepWindow(base time.Duration, failedProbes int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier == 0 {
+		multiplier = DefaultSleepWindowMultiplier
+	}
+
+	window := float64(base) * math.Pow(multiplier, float64(failedProbes))
+	if b.MaxSleepWindow > 0 && window > float64(b.MaxSleepWindow) {
+		window = float64(b.MaxSleepWindow)
+	}
+	if b.Jitter > 0 {
+		window -= window * b.Jitter * rand.Float64()
+	}
+
+	return time.Duration(math.Max(window, float64(base)))
+}
+
+// EffectiveSleepWindow returns how long the circuit currently waits after opening, or after its last probe,
+// before probing for recovery again.
+func (circuit *CircuitBreaker) EffectiveSleepWindow() time.Duration {
+	if window := atomic.LoadInt64(&circuit.sleepWindow); window > 0 {
+		return time.Duration(window)
+	}
+	return getSettings(circuit.Name).SleepWindow
+}
+
+// probeFailed grows the sleep window according to the SleepWindowBackoff of the circuit, after a half-open
+// probe ran and did not succeed.
+func (circuit *CircuitBreaker) probeFailed() {
+	settings := getSettings(circuit.Name)
+	if settings.SleepWindowBackoff == nil {
+		return
+	}
+
+	failedProbes := atomic.AddInt32(&circuit.failedProbes, 1)
+	window := settings.SleepWindowBackoff.sleepWindow(settings.SleepWindow, int(failedProbes))
+	atomic.StoreInt64(&circuit.sleepWindow, int64(window))
+}
+
+// resetSleepWindow returns the sleep window to its configured value.
+func (circuit *CircuitBreaker) resetSleepWindow() {
+	atomic.StoreInt32(&circuit.failedProbes, 0)
+	atomic.StoreInt64(&circuit.sleepWindow, 0)
+}
@@ -0,0 +1,98 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSleepWindowBackoff(t *testing.T) {
+	Convey("with a backoff doubling the sleep window up to 1 minute", t, func() {
+		b := &SleepWindowBackoff{MaxSleepWindow: time.Minute}
+
+		Convey("the sleep window doubles after every failed probe", func() {
+			So(b.sleepWindow(5*time.Second, 1), ShouldEqual, 10*time.Second)
+			So(b.sleepWindow(5*time.Second, 2), ShouldEqual, 20*time.Second)
+		})
+
+		Convey("the sleep window never exceeds the cap", func() {
+			So(b.sleepWindow(5*time.Second, 10), ShouldEqual, time.Minute)
+		})
+
+		Convey("jitter never shortens the sleep window below the configured one", func() {
+			b.Jitter = 1
+			So(b.sleepWindow(5*time.Second, 1), ShouldBeBetweenOrEqual, 5*time.Second, 10*time.Second)
+		})
+	})
+}
+
+// passSleepWindow moves the time the circuit opened or was last probed back by its sleep window, as if it had passed.
+func passSleepWindow(cb *CircuitBreaker) {
+	atomic.AddInt64(&cb.openedOrLastTestedTime, -int64(cb.EffectiveSleepWindow())-1)
+}
+
+func TestEffectiveSleepWindow(t *testing.T) {
+	Convey("with an open circuit whose sleep window backs off", t, func() {
+		defer Flush()
+		ConfigureCommand("backoff", CommandConfig{SleepWindow: 1000, MaxSleepWindow: 60000})
+		cb, _, _ := GetCircuit("backoff")
+		cb.setOpen()
+
+		So(cb.EffectiveSleepWindow(), ShouldEqual, time.Second)
+
+		Convey("a failed probe doubles the sleep window", func() {
+			passSleepWindow(cb)
+			_ = Do("backoff", func() error {
+				return fmt.Errorf("still down")
+			}, nil)
+
+			So(cb.EffectiveSleepWindow(), ShouldEqual, 2*time.Second)
+
+			Convey("and requests within it are short-circuited", func() {
+				So(cb.AllowRequest(), ShouldBeFalse)
+			})
+
+			Convey("and closing the circuit resets it", func() {
+				cb.setClose()
+				So(cb.EffectiveSleepWindow(), ShouldEqual, time.Second)
+			})
+		})
+	})
+	Convey("with an open circuit whose sleep window backs off and whose rate limit is used up", t, func() {
+		defer Flush()
+		ConfigureCommand("backoff_limited", CommandConfig{SleepWindow: 1000, MaxSleepWindow: 60000, RateLimit: 0.5})
+		So(Do("backoff_limited", func() error { return nil }, nil), ShouldBeNil)
+		cb, _, _ := GetCircuit("backoff_limited")
+		cb.setOpen()
+
+		Convey("a probe turned away by the rate limit leaves the sleep window alone", func() {
+			passSleepWindow(cb)
+			So(DoC(context.Background(), "backoff_limited", func(ctx context.Context) error { return nil }, nil), ShouldResemble, ErrRateLimited)
+
+			So(cb.EffectiveSleepWindow(), ShouldEqual, time.Second)
+		})
+	})
+
+	Convey("with an open circuit whose sleep window backs off", t, func() {
+		defer Flush()
+		ConfigureCommand("backoff_canceled", CommandConfig{SleepWindow: 1000, MaxSleepWindow: 60000})
+		cb, _, _ := GetCircuit("backoff_canceled")
+		cb.setOpen()
+
+		Convey("a probe canceled by its caller leaves the sleep window alone", func() {
+			passSleepWindow(cb)
+			ctx, cancel := context.WithCancel(context.Background())
+			err := DoC(ctx, "backoff_canceled", func(ctx context.Context) error {
+				cancel()
+				return ctx.Err()
+			}, nil)
+			So(err == context.Canceled, ShouldBeTrue)
+
+			So(cb.EffectiveSleepWindow(), ShouldEqual, time.Second)
+		})
+	})
+}
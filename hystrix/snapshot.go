@@ -0,0 +1,207 @@
+package hystrix
+
+import (
+	"sort"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/rolling"
+)
+
+// ErrUnknownCircuit occurs when reading the metrics of a circuit which has not been created yet.
+var ErrUnknownCircuit = CircuitError{Message: "unknown circuit"}
+
+// Snapshot is a copy of the metrics of a circuit at a point in time, as published to the dashboard.
+// Counts are summed over the rolling statistical window and latencies over the rolling percentile window.
+type Snapshot struct {
+	Name         string
+	CommandGroup string
+	Time         time.Time
+
+	// Open is set when the circuit currently short-circuits commands, ForceOpen when it was opened manually
+	Open                 bool
+	ForceOpen            bool
+	EffectiveSleepWindow time.Duration
+
+	Requests     uint64
+	Errors       uint64
+	ErrorPercent int
+	Counts       SnapshotCounts
+
+	TotalLatency LatencySnapshot
+	RunLatency   LatencySnapshot
+
+	Pool PoolSnapshot
+
+	// DroppedMetricUpdates counts the updates which did not reach one of the metric collectors of the circuit
+	// since it was created, because they could not keep up
+	DroppedMetricUpdates uint64
+
+	// Settings are the settings of the circuit when the snapshot was taken
+	Settings Settings
+}
+
+// SnapshotCounts holds the rolling counts of each event of a circuit.
+type SnapshotCounts struct {
+	Successes          uint64
+	Failures           uint64
+	Rejects            uint64
+	ShortCircuits      uint64
+	Timeouts           uint64
+	QueueSize          uint64
+	FallbackSuccesses  uint64
+	FallbackFailures   uint64
+	Retries            uint64
+	Hedges             uint64
+	HedgeWins          uint64
+	CollapsedRequests  uint64
+	ResponsesFromCache uint64
+	StaleServed        uint64
+	StaleMisses        uint64
+	RateLimited        uint64
+	RampShortCircuits  uint64
+}
+
+// LatencySnapshot holds the mean and percentiles of durations.
+type LatencySnapshot struct {
+	Mean time.Duration
+	P0   time.Duration
+	P25  time.Duration
+	P50  time.Duration
+	P75  time.Duration
+	P90  time.Duration
+	P95  time.Duration
+	P99  time.Duration
+	P995 time.Duration
+	P100 time.Duration
+}
+
+// PoolSnapshot holds the state of the executor pool of a circuit.
+type PoolSnapshot struct {
+	Name                        string
+	MaxConcurrentRequests       int
+	QueueSizeRejectionThreshold int
+	Active                      int
+	Waiting                     int
+	// MaxActive and Executed are rolling over the statistical window
+	MaxActive uint64
+	Executed  uint64
+}
+
+// GetMetrics returns a snapshot of the metrics of the named circuit, or ErrUnknownCircuit when no command
+// with that name has been executed.
+func GetMetrics(name string) (Snapshot, error) {
+	circuitBreakersMutex.RLock()
+	cb, ok := circuitBreakers[name]
+	circuitBreakersMutex.RUnlock()
+	if !ok {
+		return Snapshot{}, ErrUnknownCircuit
+	}
+
+	return cb.snapshot(time.Now()), nil
+}
+
+// AllMetrics returns a snapshot of the metrics of every circuit, sorted by name.
+func AllMetrics() []Snapshot {
+	circuitBreakersMutex.RLock()
+	circuits := make([]*CircuitBreaker, 0, len(circuitBreakers))
+	for _, cb := range circuitBreakers {
+		circuits = append(circuits, cb)
+	}
+	circuitBreakersMutex.RUnlock()
+
+	now := time.Now()
+	snapshots := make([]Snapshot, len(circuits))
+	for i, cb := range circuits {
+		snapshots[i] = cb.snapshot(now)
+	}
+	sort.Sort(byName(snapshots))
+
+	return snapshots
+}
+
+func (circuit *CircuitBreaker) snapshot(now time.Time) Snapshot {
+	collector := circuit.metrics.DefaultCollector()
+	pool := circuit.executorPool
+	count := func(n *rolling.Number) uint64 {
+		return uint64(n.Sum(now))
+	}
+
+	circuit.mutex.RLock()
+	forceOpen := circuit.forceOpen
+	circuit.mutex.RUnlock()
+
+	pool.Metrics.Mutex.RLock()
+	maxActive := uint64(pool.Metrics.MaxActiveRequests.Max(now))
+	executed := count(pool.Metrics.Executed)
+	pool.Metrics.Mutex.RUnlock()
+
+	return Snapshot{
+		Name:                 circuit.Name,
+		CommandGroup:         circuit.CommandGroup,
+		Time:                 now,
+		Open:                 circuit.IsOpen(),
+		ForceOpen:            forceOpen,
+		EffectiveSleepWindow: circuit.EffectiveSleepWindow(),
+
+		Requests:     count(circuit.metrics.Requests()),
+		Errors:       count(collector.Errors()),
+		ErrorPercent: circuit.metrics.ErrorPercent(now),
+		Counts: SnapshotCounts{
+			Successes:          count(collector.Successes()),
+			Failures:           count(collector.Failures()),
+			Rejects:            count(collector.Rejects()),
+			ShortCircuits:      count(collector.ShortCircuits()),
+			Timeouts:           count(collector.Timeouts()),
+			QueueSize:          count(collector.QueueSize()),
+			FallbackSuccesses:  count(collector.FallbackSuccesses()),
+			FallbackFailures:   count(collector.FallbackFailures()),
+			Retries:            count(collector.Retries()),
+			Hedges:             count(collector.Hedges()),
+			HedgeWins:          count(collector.HedgeWins()),
+			CollapsedRequests:  count(collector.CollapsedRequests()),
+			ResponsesFromCache: count(collector.ResponsesFromCache()),
+			StaleServed:        count(collector.StaleServed()),
+			StaleMisses:        count(collector.StaleMisses()),
+			RateLimited:        count(collector.RateLimited()),
+			RampShortCircuits:  count(collector.RampShortCircuits()),
+		},
+
+		TotalLatency: latencySnapshot(collector.TotalDuration()),
+		RunLatency:   latencySnapshot(collector.RunDuration()),
+
+		Pool: PoolSnapshot{
+			Name:                        pool.Name,
+			MaxConcurrentRequests:       pool.Max,
+			QueueSizeRejectionThreshold: pool.QueueSizeRejectionThreshold,
+			Active:                      pool.ActiveCount(),
+			Waiting:                     pool.WaitingCount(),
+			MaxActive:                   maxActive,
+			Executed:                    executed,
+		},
+
+		DroppedMetricUpdates: circuit.metrics.DroppedUpdates(),
+
+		Settings: getSettings(circuit.Name).clone(),
+	}
+}
+
+func latencySnapshot(r *rolling.Timing) LatencySnapshot {
+	return LatencySnapshot{
+		Mean: r.MeanDuration(),
+		P0:   r.PercentileDuration(0),
+		P25:  r.PercentileDuration(25),
+		P50:  r.PercentileDuration(50),
+		P75:  r.PercentileDuration(75),
+		P90:  r.PercentileDuration(90),
+		P95:  r.PercentileDuration(95),
+		P99:  r.PercentileDuration(99),
+		P995: r.PercentileDuration(99.5),
+		P100: r.PercentileDuration(100),
+	}
+}
+
+type byName []Snapshot
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
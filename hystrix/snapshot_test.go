@@ -0,0 +1,71 @@
+package hystrix
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetMetrics(t *testing.T) {
+	Convey("with a circuit which succeeded once and failed once", t, func() {
+		defer Flush()
+		ConfigureCommand("snapshot", CommandConfig{ErrorPercentThreshold: 90, RetryMaxAttempts: 2})
+		_ = Do("snapshot", func() error {
+			time.Sleep(2 * time.Millisecond)
+			return nil
+		}, nil)
+		_ = Do("snapshot", func() error {
+			return fmt.Errorf("run_error")
+		}, func(err error) error {
+			return nil
+		})
+		time.Sleep(10 * time.Millisecond)
+
+		snapshot, err := GetMetrics("snapshot")
+		So(err, ShouldBeNil)
+
+		Convey("the snapshot holds its rolling counts", func() {
+			So(snapshot.Name, ShouldEqual, "snapshot")
+			So(snapshot.Requests, ShouldEqual, 2)
+			So(snapshot.Errors, ShouldEqual, 1)
+			So(snapshot.ErrorPercent, ShouldEqual, 50)
+			So(snapshot.Counts.Successes, ShouldEqual, 1)
+			So(snapshot.Counts.Failures, ShouldEqual, 1)
+			So(snapshot.Counts.FallbackSuccesses, ShouldEqual, 1)
+		})
+
+		Convey("the snapshot holds its latencies", func() {
+			So(snapshot.RunLatency.P100, ShouldBeGreaterThanOrEqualTo, 2*time.Millisecond)
+			So(snapshot.TotalLatency.P100, ShouldBeGreaterThanOrEqualTo, snapshot.RunLatency.P100)
+		})
+
+		Convey("the snapshot holds its state and settings", func() {
+			So(snapshot.Open, ShouldBeFalse)
+			So(snapshot.Settings.ErrorPercentThreshold, ShouldEqual, 90)
+			So(snapshot.Pool.MaxConcurrentRequests, ShouldEqual, DefaultMaxConcurrent)
+			So(snapshot.Pool.Active, ShouldEqual, 0)
+		})
+
+		Convey("changing the settings of the snapshot leaves the circuit alone", func() {
+			snapshot.Settings.Retry.MaxAttempts = 5
+			So(getSettings("snapshot").Retry.MaxAttempts, ShouldEqual, 2)
+		})
+
+		Convey("AllMetrics includes the snapshot", func() {
+			all := AllMetrics()
+			So(len(all), ShouldEqual, 1)
+			So(all[0].Name, ShouldEqual, "snapshot")
+		})
+	})
+
+	Convey("with no circuit", t, func() {
+		defer Flush()
+
+		Convey("GetMetrics returns an error", func() {
+			_, err := GetMetrics("unknown")
+			So(err, ShouldResemble, ErrUnknownCircuit)
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package hystrix
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// ErrNoStaleValue occurs when a command of a StaleCache failed and no result younger than the TTL was stored for its cache key.
+var ErrNoStaleValue = CircuitError{Message: "no stale value"}
+
+// StaleCache is a fallback provider which remembers the last successful result of commands per cache key.
+// When a command fails, times out or is short-circuited, the remembered result is served instead, as long as
+// it is younger than the TTL. Entries are kept in memory and the least recently used ones are evicted.
+type StaleCache struct {
+	size int
+	ttl  time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type staleCacheEntry struct {
+	key    string
+	value  interface{}
+	stored time.Time
+}
+
+type staleValue struct {
+	value interface{}
+}
+
+// NewStaleCache creates a StaleCache holding at most size entries, each served for at most ttl after it was stored.
+func NewStaleCache(size int, ttl time.Duration) *StaleCache {
+	return &StaleCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Do runs your function in a synchronous manner like DoC, returning the value it produced and storing it under
+// the cache key. When the command fails, the last stored value is returned instead and flagged as stale.
+// Serving a stale value is recorded as a "stale-served" event of the command, and finding none as a "stale-miss" event,
+// in which case the fallback of the command fails with ErrNoStaleValue.
+func (c *StaleCache) Do(ctx context.Context, name string, cacheKey string, run valueFuncC) (value interface{}, stale bool, err error) {
+	key := name + "\x00" + cacheKey
+
+	value, err = doValue(ctx, name, func(ctx context.Context) (interface{}, error) {
+		result, runErr := run(ctx)
+		if runErr != nil {
+			return nil, runErr
+		}
+
+		c.store(key, result)
+		return result, nil
+	}, func(ctx context.Context, runErr error) (interface{}, error) {
+		result, ok := c.load(key)
+		if !ok {
+			reportEventFromContext(ctx, metricCollector.EventStaleMiss)
+			return nil, ErrNoStaleValue
+		}
+
+		reportEventFromContext(ctx, metricCollector.EventStaleServed)
+		return staleValue{value: result}, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if s, ok := value.(staleValue); ok {
+		return s.value, true, nil
+	}
+	return value, false, nil
+}
+
+func (c *StaleCache) store(key string, value interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*staleCacheEntry)
+		entry.value = value
+		entry.stored = time.Now()
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&staleCacheEntry{key: key, value: value, stored: time.Now()})
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *StaleCache) load(key string) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*staleCacheEntry)
+	if time.Since(entry.stored) > c.ttl {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+	return entry.value, true
+}
+
+func (c *StaleCache) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*staleCacheEntry).key)
+}
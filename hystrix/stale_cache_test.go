@@ -0,0 +1,85 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func staleRun(value interface{}, err error) valueFuncC {
+	return func(ctx context.Context) (interface{}, error) {
+		return value, err
+	}
+}
+
+func TestStaleCache(t *testing.T) {
+	Convey("with a stale cache holding a successful result", t, func() {
+		defer Flush()
+		cache := NewStaleCache(2, time.Minute)
+
+		value, stale, err := cache.Do(context.Background(), "", "key", staleRun("fresh", nil))
+		So(err, ShouldBeNil)
+		So(stale, ShouldBeFalse)
+		So(value, ShouldEqual, "fresh")
+
+		Convey("a failing command serves the stored result as stale", func() {
+			value, stale, err := cache.Do(context.Background(), "", "key", staleRun(nil, fmt.Errorf("run_error")))
+			So(err, ShouldBeNil)
+			So(stale, ShouldBeTrue)
+			So(value, ShouldEqual, "fresh")
+
+			Convey("and the stale result is recorded", func() {
+				time.Sleep(10 * time.Millisecond)
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().StaleServed().Sum(time.Now()), ShouldEqual, 1)
+				So(cb.metrics.DefaultCollector().FallbackSuccesses().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+
+		Convey("a failing command with another cache key finds no stale value", func() {
+			_, stale, err := cache.Do(context.Background(), "", "other", staleRun(nil, fmt.Errorf("run_error")))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrNoStaleValue.Error())
+			So(err.Error(), ShouldContainSubstring, "run_error")
+			So(stale, ShouldBeFalse)
+
+			Convey("and the miss is recorded", func() {
+				time.Sleep(10 * time.Millisecond)
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().StaleMisses().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+
+		Convey("and more keys than it can hold", func() {
+			_, _, _ = cache.Do(context.Background(), "", "key2", staleRun("two", nil))
+			_, _, _ = cache.Do(context.Background(), "", "key3", staleRun("three", nil))
+
+			Convey("the least recently used result is evicted", func() {
+				_, _, err := cache.Do(context.Background(), "", "key", staleRun(nil, fmt.Errorf("run_error")))
+				So(err, ShouldNotBeNil)
+
+				value, stale, err := cache.Do(context.Background(), "", "key3", staleRun(nil, fmt.Errorf("run_error")))
+				So(err, ShouldBeNil)
+				So(stale, ShouldBeTrue)
+				So(value, ShouldEqual, "three")
+			})
+		})
+	})
+
+	Convey("with a stale cache whose results have expired", t, func() {
+		defer Flush()
+		cache := NewStaleCache(2, 10*time.Millisecond)
+
+		_, _, _ = cache.Do(context.Background(), "", "key", staleRun("fresh", nil))
+		time.Sleep(20 * time.Millisecond)
+
+		Convey("a failing command returns its error", func() {
+			_, stale, err := cache.Do(context.Background(), "", "key", staleRun(nil, fmt.Errorf("run_error")))
+			So(err, ShouldNotBeNil)
+			So(stale, ShouldBeFalse)
+		})
+	})
+}
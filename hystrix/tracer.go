@@ -0,0 +1,46 @@
+package hystrix
+
+import (
+	"context"
+	"sync"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// Tracer traces every execution of a command run with GoC or DoC, such as with a span wrapping the run
+// and fallback functions. It is set with SetTracer.
+type Tracer interface {
+	// StartExecution is called with the context passed to GoC before the command runs. The returned context
+	// is passed to the run and fallback functions, and end is called with the result of the execution once
+	// it has been recorded, before the caller is told about it.
+	StartExecution(ctx context.Context, name string) (context.Context, func(result metricCollector.ExecutionResult))
+}
+
+var (
+	tracerMutex *sync.RWMutex
+	tracer      Tracer
+)
+
+func init() {
+	tracerMutex = &sync.RWMutex{}
+}
+
+// SetTracer sets the Tracer of the commands started afterwards. A nil tracer disables tracing.
+func SetTracer(t Tracer) {
+	tracerMutex.Lock()
+	defer tracerMutex.Unlock()
+
+	tracer = t
+}
+
+// startExecution starts tracing the execution of the command with the tracer set, if any.
+func startExecution(ctx context.Context, name string) (context.Context, func(result metricCollector.ExecutionResult)) {
+	tracerMutex.RLock()
+	t := tracer
+	tracerMutex.RUnlock()
+
+	if t == nil {
+		return ctx, func(metricCollector.ExecutionResult) {}
+	}
+	return t.StartExecution(ctx, name)
+}
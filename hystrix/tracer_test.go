@@ -0,0 +1,55 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type spanKey struct{}
+
+type recordingTracer struct {
+	started []string
+	ended   []metricCollector.ExecutionResult
+}
+
+func (t *recordingTracer) StartExecution(ctx context.Context, name string) (context.Context, func(result metricCollector.ExecutionResult)) {
+	t.started = append(t.started, name)
+	return context.WithValue(ctx, spanKey{}, name), func(result metricCollector.ExecutionResult) {
+		t.ended = append(t.ended, result)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	Convey("with a tracer set", t, func() {
+		defer Flush()
+		tracer := &recordingTracer{}
+		SetTracer(tracer)
+		defer SetTracer(nil)
+
+		Convey("a failing command is traced around its run and fallback functions", func() {
+			var runSpan, fallbackSpan interface{}
+			err := DoC(context.Background(), "traced", func(ctx context.Context) error {
+				runSpan = ctx.Value(spanKey{})
+				return fmt.Errorf("run_error")
+			}, func(ctx context.Context, err error) error {
+				fallbackSpan = ctx.Value(spanKey{})
+				return nil
+			})
+			So(err, ShouldBeNil)
+
+			So(tracer.started, ShouldResemble, []string{"traced"})
+			So(runSpan, ShouldEqual, "traced")
+			So(fallbackSpan, ShouldEqual, "traced")
+
+			Convey("and the trace ends with its result before DoC returns", func() {
+				So(len(tracer.ended), ShouldEqual, 1)
+				So(tracer.ended[0].Events, ShouldResemble, []metricCollector.EventType{metricCollector.EventFailure, metricCollector.EventFallbackSuccess})
+				So(tracer.ended[0].TotalDuration, ShouldBeGreaterThan, 0)
+			})
+		})
+	})
+}
@@ -54,6 +54,7 @@ func main() {
 		log.Fatalf("could not initialize statsd client: %v", err)
 	}
 	metricCollector.Registry.Register(c.NewStatsdCollector)
+	plugins.PublishExpvar()
 
 	hystrix.ConfigureCommand("test", hystrix.CommandConfig{
 		Timeout: 50,
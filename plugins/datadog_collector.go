@@ -28,6 +28,8 @@ const (
 	dmFallbackFailures  = "hystrix.fallbackFailures"
 	dmTotalDuration     = "hystrix.totalDuration"
 	dmRunDuration       = "hystrix.runDuration"
+	dmPoolActive        = "hystrix.poolActive"
+	dmPoolWaiting       = "hystrix.poolWaiting"
 )
 
 type (
@@ -140,7 +142,6 @@ func (dc *DatadogCollector) IncrementErrors() {
 
 // IncrementSuccesses increments the number of requests that succeed.
 func (dc *DatadogCollector) IncrementSuccesses() {
-	_ = dc.client.Gauge(dmCircuitOpen, 0, dc.tags, 1.0)
 	_ = dc.client.Count(dmSuccesses, 1, dc.tags, 1.0)
 }
 
@@ -157,7 +158,6 @@ func (dc *DatadogCollector) IncrementRejects() {
 // IncrementShortCircuits increments the number of requests that short circuited
 // due to the circuit being open.
 func (dc *DatadogCollector) IncrementShortCircuits() {
-	_ = dc.client.Gauge(dmCircuitOpen, 1, dc.tags, 1.0)
 	_ = dc.client.Count(dmShortCircuits, 1, dc.tags, 1.0)
 }
 
@@ -191,5 +191,17 @@ func (dc *DatadogCollector) UpdateRunDuration(runDuration time.Duration) {
 	_ = dc.client.TimeInMilliseconds(dmRunDuration, ms, dc.tags, 1.0)
 }
 
+// UpdateCircuitState records whether the circuit is open and the number of executions holding and waiting for a ticket.
+// These register as gauges in the Datadog collector.
+func (dc *DatadogCollector) UpdateCircuitState(state metricCollector.CircuitState) {
+	open := float64(0)
+	if state.Open {
+		open = 1
+	}
+	_ = dc.client.Gauge(dmCircuitOpen, open, dc.tags, 1.0)
+	_ = dc.client.Gauge(dmPoolActive, float64(state.ActiveCount), dc.tags, 1.0)
+	_ = dc.client.Gauge(dmPoolWaiting, float64(state.WaitingCount), dc.tags, 1.0)
+}
+
 // Reset is a noop operation in this collector.
 func (dc *DatadogCollector) Reset() {}
@@ -4,6 +4,7 @@ import (
 	"sync/atomic"
 	"testing"
 
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
 	"github.com/myteksi/hystrix-go/plugins/mocks"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/mock"
@@ -30,3 +31,27 @@ func TestNewDatadogCollector(t *testing.T) {
 		So(1, ShouldEqual, error1)
 	})
 }
+
+func TestDatadogCircuitState(t *testing.T) {
+	mockDatadog := &mocks.DatadogClient{}
+	tags := []string{"hystrixcircuit:commandName1", "commandGroup:commandGroup1"}
+	mockDatadog.On("Gauge", "hystrix.circuitOpen", float64(1), tags, float64(1)).Return(nil)
+	mockDatadog.On("Gauge", "hystrix.poolActive", float64(3), tags, float64(1)).Return(nil)
+	mockDatadog.On("Gauge", "hystrix.poolWaiting", float64(2), tags, float64(1)).Return(nil)
+	collector := NewDatadogCollectorWithClient(mockDatadog)("commandName1", "commandGroup1").(*DatadogCollector)
+
+	Convey("update circuit state", t, func() {
+		collector.UpdateCircuitState(metricCollector.CircuitState{Open: true, ActiveCount: 3, WaitingCount: 2, MaxConcurrentRequests: 10})
+
+		So(mockDatadog.AssertNumberOfCalls(t, "Gauge", 3), ShouldBeTrue)
+	})
+
+	Convey("short circuits leave the circuit open gauge to the circuit state", t, func() {
+		mockDatadog.On("Count", "hystrix.shortCircuits", int64(1), tags, float64(1)).Return(nil)
+		mockDatadog.On("Count", "hystrix.successes", int64(1), tags, float64(1)).Return(nil)
+		collector.IncrementShortCircuits()
+		collector.IncrementSuccesses()
+
+		So(mockDatadog.AssertNumberOfCalls(t, "Gauge", 3), ShouldBeTrue)
+	})
+}
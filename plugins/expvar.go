@@ -0,0 +1,135 @@
+package plugins
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+)
+
+var publishExpvarOnce sync.Once
+
+// expvarCircuit is the state of a circuit as published to expvar. Latencies are in milliseconds.
+type expvarCircuit struct {
+	Open         bool               `json:"open"`
+	ForceOpen    bool               `json:"force_open"`
+	Requests     uint64             `json:"requests"`
+	Errors       uint64             `json:"errors"`
+	ErrorPercent int                `json:"error_percent"`
+	Counts       expvarCounts       `json:"counts"`
+	RunLatency   map[string]float64 `json:"run_latency_ms"`
+	TotalLatency map[string]float64 `json:"total_latency_ms"`
+	Pool         expvarPool         `json:"pool"`
+}
+
+// expvarCounts are the rolling counts of each event of a circuit as published to expvar.
+type expvarCounts struct {
+	Successes          uint64 `json:"successes"`
+	Failures           uint64 `json:"failures"`
+	Rejects            uint64 `json:"rejects"`
+	ShortCircuits      uint64 `json:"short_circuits"`
+	Timeouts           uint64 `json:"timeouts"`
+	QueueSize          uint64 `json:"queue_size"`
+	FallbackSuccesses  uint64 `json:"fallback_successes"`
+	FallbackFailures   uint64 `json:"fallback_failures"`
+	Retries            uint64 `json:"retries"`
+	Hedges             uint64 `json:"hedges"`
+	HedgeWins          uint64 `json:"hedge_wins"`
+	CollapsedRequests  uint64 `json:"collapsed_requests"`
+	ResponsesFromCache uint64 `json:"responses_from_cache"`
+	StaleServed        uint64 `json:"stale_served"`
+	StaleMisses        uint64 `json:"stale_misses"`
+	RateLimited        uint64 `json:"rate_limited"`
+	RampShortCircuits  uint64 `json:"ramp_short_circuits"`
+}
+
+// expvarPool is the state of the executor pool of a circuit as published to expvar.
+type expvarPool struct {
+	Name                        string `json:"name"`
+	MaxConcurrentRequests       int    `json:"max_concurrent_requests"`
+	QueueSizeRejectionThreshold int    `json:"queue_size_rejection_threshold"`
+	Active                      int    `json:"active"`
+	Waiting                     int    `json:"waiting"`
+	MaxActive                   uint64 `json:"max_active"`
+	Executed                    uint64 `json:"executed"`
+}
+
+// PublishExpvar publishes the state of every circuit as the "hystrix" variable of the expvar package,
+// served at /debug/vars. The variable is computed whenever it is read, so it follows circuits as they
+// are created and flushed. Calling it more than once has no effect.
+func PublishExpvar() {
+	publishExpvarOnce.Do(func() {
+		expvar.Publish("hystrix", expvar.Func(expvarCircuits))
+	})
+}
+
+func expvarCircuits() interface{} {
+	circuits := make(map[string]expvarCircuit)
+	for _, s := range hystrix.AllMetrics() {
+		circuits[s.Name] = expvarCircuit{
+			Open:         s.Open,
+			ForceOpen:    s.ForceOpen,
+			Requests:     s.Requests,
+			Errors:       s.Errors,
+			ErrorPercent: s.ErrorPercent,
+			Counts:       newExpvarCounts(s.Counts),
+			RunLatency:   expvarLatency(s.RunLatency),
+			TotalLatency: expvarLatency(s.TotalLatency),
+			Pool:         newExpvarPool(s.Pool),
+		}
+	}
+	return circuits
+}
+
+func newExpvarCounts(c hystrix.SnapshotCounts) expvarCounts {
+	return expvarCounts{
+		Successes:          c.Successes,
+		Failures:           c.Failures,
+		Rejects:            c.Rejects,
+		ShortCircuits:      c.ShortCircuits,
+		Timeouts:           c.Timeouts,
+		QueueSize:          c.QueueSize,
+		FallbackSuccesses:  c.FallbackSuccesses,
+		FallbackFailures:   c.FallbackFailures,
+		Retries:            c.Retries,
+		Hedges:             c.Hedges,
+		HedgeWins:          c.HedgeWins,
+		CollapsedRequests:  c.CollapsedRequests,
+		ResponsesFromCache: c.ResponsesFromCache,
+		StaleServed:        c.StaleServed,
+		StaleMisses:        c.StaleMisses,
+		RateLimited:        c.RateLimited,
+		RampShortCircuits:  c.RampShortCircuits,
+	}
+}
+
+func newExpvarPool(p hystrix.PoolSnapshot) expvarPool {
+	return expvarPool{
+		Name:                        p.Name,
+		MaxConcurrentRequests:       p.MaxConcurrentRequests,
+		QueueSizeRejectionThreshold: p.QueueSizeRejectionThreshold,
+		Active:                      p.Active,
+		Waiting:                     p.Waiting,
+		MaxActive:                   p.MaxActive,
+		Executed:                    p.Executed,
+	}
+}
+
+func expvarLatency(l hystrix.LatencySnapshot) map[string]float64 {
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+	return map[string]float64{
+		"mean": ms(l.Mean),
+		"0":    ms(l.P0),
+		"25":   ms(l.P25),
+		"50":   ms(l.P50),
+		"75":   ms(l.P75),
+		"90":   ms(l.P90),
+		"95":   ms(l.P95),
+		"99":   ms(l.P99),
+		"99.5": ms(l.P995),
+		"100":  ms(l.P100),
+	}
+}
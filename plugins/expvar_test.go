@@ -0,0 +1,41 @@
+package plugins
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	Convey("with the circuits published to expvar", t, func() {
+		defer hystrix.Flush()
+		PublishExpvar()
+		PublishExpvar()
+
+		Convey("a new circuit appears once it ran a command", func() {
+			_ = hystrix.Do("expvar", func() error {
+				return nil
+			}, nil)
+
+			var circuits map[string]expvarCircuit
+			So(json.Unmarshal([]byte(expvar.Get("hystrix").String()), &circuits), ShouldBeNil)
+			So(circuits, ShouldContainKey, "expvar")
+			So(circuits["expvar"].Open, ShouldBeFalse)
+			So(circuits["expvar"].Requests, ShouldEqual, 1)
+			So(circuits["expvar"].Counts.Successes, ShouldEqual, 1)
+			So(circuits["expvar"].Pool.MaxConcurrentRequests, ShouldEqual, hystrix.DefaultMaxConcurrent)
+			So(circuits["expvar"].RunLatency, ShouldContainKey, "99")
+			So(expvar.Get("hystrix").String(), ShouldContainSubstring, `"successes":1`)
+			So(expvar.Get("hystrix").String(), ShouldContainSubstring, `"max_concurrent_requests":`)
+
+			Convey("and disappears once the circuits are flushed", func() {
+				hystrix.Flush()
+
+				So(expvar.Get("hystrix").String(), ShouldEqual, "{}")
+			})
+		})
+	})
+}
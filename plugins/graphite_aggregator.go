@@ -13,6 +13,7 @@ import (
 
 var makeTimerFunc = func() interface{} { return metrics.NewTimer() }
 var makeCounterFunc = func() interface{} { return metrics.NewCounter() }
+var makeGaugeFunc = func() interface{} { return metrics.NewGauge() }
 
 // GraphiteCollector fulfills the metricCollector interface allowing users to ship circuit
 // stats to a graphite backend. To use users must call InitializeGraphiteCollector before
@@ -21,6 +22,7 @@ var makeCounterFunc = func() interface{} { return metrics.NewCounter() }
 // This Collector uses github.com/rcrowley/go-metrics for aggregation. See that repo for more details
 // on how metrics are aggregated and expressed in graphite.
 type GraphiteCollector struct {
+	circuitOpenPrefix       string
 	attemptsPrefix          string
 	queueSizePrefix         string
 	errorsPrefix            string
@@ -33,6 +35,8 @@ type GraphiteCollector struct {
 	fallbackFailuresPrefix  string
 	totalDurationPrefix     string
 	runDurationPrefix       string
+	poolActivePrefix        string
+	poolWaitingPrefix       string
 }
 
 // GraphiteCollectorConfig provides configuration that the graphite client will need.
@@ -59,6 +63,7 @@ func NewGraphiteCollector(name string, commandGroup string) metricCollector.Metr
 	name = strings.Replace(name, ":", "-", -1)
 	name = strings.Replace(name, ".", "-", -1)
 	return &GraphiteCollector{
+		circuitOpenPrefix:       commandGroup + "." + name + ".circuitOpen",
 		attemptsPrefix:          commandGroup + "." + name + ".attempts",
 		errorsPrefix:            commandGroup + "." + name + ".errors",
 		queueSizePrefix:         commandGroup + "." + name + ".queueLength",
@@ -71,6 +76,8 @@ func NewGraphiteCollector(name string, commandGroup string) metricCollector.Metr
 		fallbackFailuresPrefix:  commandGroup + "." + name + ".fallbackFailures",
 		totalDurationPrefix:     commandGroup + "." + name + ".totalDuration",
 		runDurationPrefix:       commandGroup + "." + name + ".runDuration",
+		poolActivePrefix:        commandGroup + "." + name + ".poolActive",
+		poolWaitingPrefix:       commandGroup + "." + name + ".poolWaiting",
 	}
 }
 
@@ -82,6 +89,14 @@ func (g *GraphiteCollector) incrementCounterMetric(prefix string) {
 	c.Inc(1)
 }
 
+func (g *GraphiteCollector) setGauge(prefix string, value int64) {
+	c, ok := metrics.GetOrRegister(prefix, makeGaugeFunc).(metrics.Gauge)
+	if !ok {
+		return
+	}
+	c.Update(value)
+}
+
 func (g *GraphiteCollector) updateTimerMetric(prefix string, dur time.Duration) {
 	c, ok := metrics.GetOrRegister(prefix, makeTimerFunc).(metrics.Timer)
 	if !ok {
@@ -166,5 +181,17 @@ func (g *GraphiteCollector) UpdateRunDuration(runDuration time.Duration) {
 	g.updateTimerMetric(g.runDurationPrefix, runDuration)
 }
 
+// UpdateCircuitState records whether the circuit is open and the number of executions holding and waiting for a ticket.
+// These register as gauges in the graphite collector.
+func (g *GraphiteCollector) UpdateCircuitState(state metricCollector.CircuitState) {
+	open := int64(0)
+	if state.Open {
+		open = 1
+	}
+	g.setGauge(g.circuitOpenPrefix, open)
+	g.setGauge(g.poolActivePrefix, int64(state.ActiveCount))
+	g.setGauge(g.poolWaitingPrefix, int64(state.WaitingCount))
+}
+
 // Reset is a noop operation in this collector.
 func (g *GraphiteCollector) Reset() {}
@@ -0,0 +1,23 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	"github.com/rcrowley/go-metrics"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGraphiteCircuitState(t *testing.T) {
+	Convey("with a graphite collector", t, func() {
+		collector := NewGraphiteCollector("graphite/state", "group").(*GraphiteCollector)
+
+		Convey("the circuit state is recorded as gauges", func() {
+			collector.UpdateCircuitState(metricCollector.CircuitState{Open: true, ActiveCount: 3, WaitingCount: 2, MaxConcurrentRequests: 10})
+
+			So(metrics.Get("group.graphite-state.circuitOpen").(metrics.Gauge).Value(), ShouldEqual, 1)
+			So(metrics.Get("group.graphite-state.poolActive").(metrics.Gauge).Value(), ShouldEqual, 3)
+			So(metrics.Get("group.graphite-state.poolWaiting").(metrics.Gauge).Value(), ShouldEqual, 2)
+		})
+	})
+}
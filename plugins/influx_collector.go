@@ -0,0 +1,280 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// InfluxCollector fulfills the metricCollector.MetricCollectorV2 interface allowing users to ship circuit
+// stats to InfluxDB or Telegraf. To use users must call InitializeInfluxCollector before circuits are started.
+// Then register the NewInfluxCollector method of the returned client with
+// metricCollector.Registry.RegisterV2(client.NewInfluxCollector).
+//
+// Every execution is written as a point in line protocol, tagged with its circuit and command group when they
+// are set, with a field counting each of its events, such as "fallback_success=1i", its durations and the state
+// of the circuit.
+type InfluxCollector struct {
+	client *InfluxCollectorClient
+	tags   string
+}
+
+// InfluxCollectorClient batches the points of every circuit and sends them to the configured endpoint.
+type InfluxCollectorClient struct {
+	mutex       sync.Mutex
+	batch       bytes.Buffer
+	size        int
+	maxSize     int
+	measurement string
+	send        func(batch []byte) error
+	// batches holds the full batches waiting to be sent, so that the collectors never wait for the endpoint.
+	batches   chan influxBatch
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// influxBatch is a batch of points in line protocol, taken from the client to be sent.
+type influxBatch struct {
+	points []byte
+	size   int
+}
+
+// influxQueueSize is how many full batches wait to be sent before further batches are dropped.
+const influxQueueSize = 10
+
+// InfluxCollectorConfig provides configuration that the Influx client will need.
+type InfluxCollectorConfig struct {
+	// Addr is the endpoint points are sent to, either "udp://host:port" or the full URL of the HTTP write
+	// endpoint, such as "http://localhost:8086/write?db=hystrix".
+	Addr string
+	// Measurement is the name of the measurement points are written to. If empty, defaults to "hystrix".
+	Measurement string
+	// FlushInterval is how often batched points are sent. If 0, defaults to 1 second.
+	FlushInterval time.Duration
+	// MaxBatchSize is the number of points sent at once at most. If 0, defaults to 100, which keeps
+	// UDP packets well below the usual limits.
+	MaxBatchSize int
+}
+
+// InitializeInfluxCollector creates the connection to the InfluxDB or Telegraf endpoint
+// and should be called before any metrics are recorded.
+//
+// Users should ensure to call Close() on the client.
+func InitializeInfluxCollector(config *InfluxCollectorConfig) (*InfluxCollectorClient, error) {
+	send, err := influxSender(config.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	measurement := config.Measurement
+	if measurement == "" {
+		measurement = "hystrix"
+	}
+	flushInterval := config.FlushInterval
+	if flushInterval == 0 {
+		flushInterval = time.Second
+	}
+	maxSize := config.MaxBatchSize
+	if maxSize == 0 {
+		maxSize = 100
+	}
+
+	c := &InfluxCollectorClient{
+		maxSize:     maxSize,
+		measurement: influxMeasurementEscaper.Replace(measurement),
+		send:        send,
+		batches:     make(chan influxBatch, influxQueueSize),
+		done:        make(chan struct{}),
+	}
+
+	go c.flushEvery(flushInterval)
+
+	return c, nil
+}
+
+// influxSender returns the function sending a batch to the endpoint.
+func influxSender(addr string) (func(batch []byte) error, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "udp":
+		conn, err := net.Dial("udp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return func(batch []byte) error {
+			_, err := conn.Write(batch)
+			return err
+		}, nil
+	case "http", "https":
+		client := &http.Client{Timeout: 5 * time.Second}
+		return func(batch []byte) error {
+			resp, err := client.Post(addr, "text/plain; charset=utf-8", bytes.NewReader(batch))
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				return fmt.Errorf("influx write failed with status %v", resp.Status)
+			}
+			return nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported influx address %q, the scheme must be udp, http or https", addr)
+	}
+}
+
+// NewInfluxCollector creates a collector for a specific circuit.
+func (c *InfluxCollectorClient) NewInfluxCollector(name string, commandGroup string) metricCollector.MetricCollectorV2 {
+	return &InfluxCollector{
+		client: c,
+		tags:   influxTags("circuit", name) + influxTags("group", commandGroup),
+	}
+}
+
+// influxTags returns the tag to append to the measurement, or nothing when the value is empty, which line
+// protocol rejects.
+func influxTags(key string, value string) string {
+	if value == "" {
+		return ""
+	}
+	return "," + key + "=" + influxTagEscaper.Replace(value)
+}
+
+// Update adds a point describing the execution to the batch.
+func (ic *InfluxCollector) Update(r metricCollector.ExecutionResult) {
+	counts := make(map[metricCollector.EventType]int)
+	var fields []string
+	for _, eventType := range r.Events {
+		if counts[eventType] == 0 {
+			fields = append(fields, string(eventType))
+		}
+		counts[eventType]++
+	}
+
+	var line bytes.Buffer
+	line.WriteString(ic.client.measurement)
+	line.WriteString(ic.tags)
+	line.WriteByte(' ')
+	for _, field := range fields {
+		line.WriteString(strings.Replace(field, "-", "_", -1))
+		line.WriteByte('=')
+		line.WriteString(strconv.Itoa(counts[metricCollector.EventType(field)]))
+		line.WriteString("i,")
+	}
+	fmt.Fprintf(&line, "run_duration_ms=%v,total_duration_ms=%v,circuit_open=%v,pool_active=%vi,pool_waiting=%vi",
+		influxMilliseconds(r.RunDuration), influxMilliseconds(r.TotalDuration), r.CircuitOpen, r.ActiveCount, r.WaitingCount)
+
+	timestamp := r.Start
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	fmt.Fprintf(&line, " %d\n", timestamp.UnixNano())
+
+	ic.client.add(line.Bytes())
+}
+
+// Reset is a noop operation in this collector.
+func (ic *InfluxCollector) Reset() {}
+
+func influxMilliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+// add appends the point to the batch. Once the batch is full, it is handed over to the flush goroutine to be
+// sent, or dropped if too many batches are already waiting.
+func (c *InfluxCollectorClient) add(point []byte) {
+	c.mutex.Lock()
+	c.batch.Write(point)
+	c.size++
+	if c.size < c.maxSize {
+		c.mutex.Unlock()
+		return
+	}
+	batch := c.takeLocked()
+	c.mutex.Unlock()
+
+	select {
+	case c.batches <- batch:
+	default:
+		log.Printf("Dropping %d points, the influx endpoint cannot keep up", batch.size)
+	}
+}
+
+func (c *InfluxCollectorClient) flushEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Flush()
+		case batch := <-c.batches:
+			c.sendBatch(batch)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Flush sends the points batched so far. The points are taken from the client before they are sent,
+// so the collectors can keep on batching while the endpoint is written to.
+func (c *InfluxCollectorClient) Flush() {
+	c.mutex.Lock()
+	batch := c.takeLocked()
+	c.mutex.Unlock()
+
+	c.sendBatch(batch)
+}
+
+// takeLocked swaps the batched points for an empty batch. The caller must hold the client mutex.
+func (c *InfluxCollectorClient) takeLocked() influxBatch {
+	batch := influxBatch{points: c.batch.Bytes(), size: c.size}
+	c.batch = bytes.Buffer{}
+	c.size = 0
+	return batch
+}
+
+func (c *InfluxCollectorClient) sendBatch(batch influxBatch) {
+	if batch.size == 0 {
+		return
+	}
+
+	err := c.send(batch.points)
+	if err != nil {
+		log.Printf("Error sending %d points to influx: %v", batch.size, err)
+	}
+}
+
+// Close stops the periodic flush and sends the remaining points. Calling it more than once has no effect.
+func (c *InfluxCollectorClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		for {
+			select {
+			case batch := <-c.batches:
+				c.sendBatch(batch)
+			default:
+				c.Flush()
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func readInfluxPacket(conn net.PacketConn) string {
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		return ""
+	}
+	return string(buf[:n])
+}
+
+func TestInfluxCollector(t *testing.T) {
+	Convey("with a collector sending to a local UDP listener", t, func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		client, err := InitializeInfluxCollector(&InfluxCollectorConfig{
+			Addr:          "udp://" + conn.LocalAddr().String(),
+			FlushInterval: time.Hour,
+			MaxBatchSize:  2,
+		})
+		So(err, ShouldBeNil)
+		defer client.Close()
+
+		collector := client.NewInfluxCollector("foo bar", "group,1")
+		start := time.Unix(1500000000, 0)
+
+		Convey("points are sent once the batch is full", func() {
+			collector.Update(metricCollector.ExecutionResult{
+				Events:        []metricCollector.EventType{metricCollector.EventFailure, metricCollector.EventFallbackSuccess},
+				Start:         start,
+				RunDuration:   10 * time.Millisecond,
+				TotalDuration: 12500 * time.Microsecond,
+				CircuitOpen:   true,
+				ActiveCount:   2,
+				WaitingCount:  1,
+			})
+			collector.Update(metricCollector.ExecutionResult{
+				Events:        []metricCollector.EventType{metricCollector.EventRetry, metricCollector.EventRetry, metricCollector.EventSuccess},
+				Start:         start,
+				RunDuration:   30 * time.Millisecond,
+				TotalDuration: 30 * time.Millisecond,
+			})
+
+			So(readInfluxPacket(conn), ShouldEqual,
+				`hystrix,circuit=foo\ bar,group=group\,1 failure=1i,fallback_success=1i,run_duration_ms=10,total_duration_ms=12.5,circuit_open=true,pool_active=2i,pool_waiting=1i 1500000000000000000`+"\n"+
+					`hystrix,circuit=foo\ bar,group=group\,1 retry=2i,success=1i,run_duration_ms=30,total_duration_ms=30,circuit_open=false,pool_active=0i,pool_waiting=0i 1500000000000000000`+"\n")
+		})
+
+		Convey("a partial batch is sent when flushed", func() {
+			collector.Update(metricCollector.ExecutionResult{
+				Events: []metricCollector.EventType{metricCollector.EventShortCircuit},
+				Start:  start,
+			})
+			client.Flush()
+
+			So(readInfluxPacket(conn), ShouldStartWith, `hystrix,circuit=foo\ bar,group=group\,1 short_circuit=1i,`)
+		})
+
+		Convey("a circuit without a command group is written without the group tag", func() {
+			client.NewInfluxCollector("foo", "").Update(metricCollector.ExecutionResult{
+				Events: []metricCollector.EventType{metricCollector.EventSuccess},
+				Start:  start,
+			})
+			client.Flush()
+
+			So(readInfluxPacket(conn), ShouldStartWith, "hystrix,circuit=foo success=1i,")
+		})
+
+		Convey("closing the client twice does not panic", func() {
+			client.Close()
+			So(client.Close, ShouldNotPanic)
+		})
+	})
+
+	Convey("with a collector flushing periodically", t, func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		client, err := InitializeInfluxCollector(&InfluxCollectorConfig{
+			Addr:          "udp://" + conn.LocalAddr().String(),
+			Measurement:   "circuits",
+			FlushInterval: 10 * time.Millisecond,
+		})
+		So(err, ShouldBeNil)
+		defer client.Close()
+
+		client.NewInfluxCollector("foo", "group").Update(metricCollector.ExecutionResult{
+			Events: []metricCollector.EventType{metricCollector.EventSuccess},
+		})
+
+		Convey("points are sent after the flush interval", func() {
+			So(readInfluxPacket(conn), ShouldStartWith, "circuits,circuit=foo,group=group success=1i,")
+		})
+	})
+
+	Convey("with a collector sending to an HTTP write endpoint", t, func() {
+		bodies := make(chan string, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, _ := ioutil.ReadAll(req.Body)
+			bodies <- req.URL.RawQuery + " " + string(body)
+			rw.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client, err := InitializeInfluxCollector(&InfluxCollectorConfig{
+			Addr:          server.URL + "/write?db=hystrix",
+			FlushInterval: time.Hour,
+		})
+		So(err, ShouldBeNil)
+
+		client.NewInfluxCollector("foo", "group").Update(metricCollector.ExecutionResult{
+			Events: []metricCollector.EventType{metricCollector.EventTimeout},
+		})
+
+		Convey("closing the client posts the remaining points", func() {
+			client.Close()
+
+			body := <-bodies
+			So(body, ShouldStartWith, "db=hystrix hystrix,circuit=foo,group=group timeout=1i,")
+			So(strings.Count(body, "\n"), ShouldEqual, 1)
+		})
+	})
+
+	Convey("with a collector sending to an HTTP write endpoint which hangs", t, func() {
+		unblock := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			<-unblock
+			rw.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		defer close(unblock)
+
+		client, err := InitializeInfluxCollector(&InfluxCollectorConfig{
+			Addr:          server.URL + "/write?db=hystrix",
+			FlushInterval: time.Hour,
+			MaxBatchSize:  1,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("updates do not wait for the endpoint", func() {
+			updated := make(chan struct{})
+			go func() {
+				collector := client.NewInfluxCollector("foo", "group")
+				for i := 0; i < 3*influxQueueSize; i++ {
+					collector.Update(metricCollector.ExecutionResult{
+						Events: []metricCollector.EventType{metricCollector.EventSuccess},
+					})
+				}
+				close(updated)
+			}()
+
+			var returned bool
+			select {
+			case <-updated:
+				returned = true
+			case <-time.After(time.Second):
+			}
+			So(returned, ShouldBeTrue)
+		})
+	})
+
+	Convey("an address with an unsupported scheme is rejected", t, func() {
+		_, err := InitializeInfluxCollector(&InfluxCollectorConfig{Addr: "tcp://localhost:8089"})
+		So(err, ShouldNotBeNil)
+	})
+}
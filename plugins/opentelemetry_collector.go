@@ -0,0 +1,150 @@
+package plugins
+
+import (
+	"context"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelInstrumentationName identifies the tracer and meter of the OpenTelemetry collector.
+const otelInstrumentationName = "github.com/myteksi/hystrix-go/plugins"
+
+// OpenTelemetryCollector fulfills the metricCollector.MetricCollectorV2 interface allowing users to
+// record the executions of a circuit as OpenTelemetry metrics. To use users must call
+// InitializeOpenTelemetryCollector before circuits are started. Then register the NewOpenTelemetryCollector
+// method of the returned client with metricCollector.Registry.RegisterV2(client.NewOpenTelemetryCollector).
+//
+// The returned client also traces every execution of a command run with hystrix.GoC or hystrix.DoC as a span,
+// see StartExecution.
+type OpenTelemetryCollector struct {
+	client     *OpenTelemetryCollectorClient
+	attributes []attribute.KeyValue
+}
+
+// OpenTelemetryCollectorClient holds the tracer and instruments shared by the collectors of every circuit.
+// It is the hystrix.Tracer of the commands once InitializeOpenTelemetryCollector returned it.
+type OpenTelemetryCollectorClient struct {
+	tracer        trace.Tracer
+	events        metric.Int64Counter
+	runDuration   metric.Float64Histogram
+	totalDuration metric.Float64Histogram
+}
+
+// OpenTelemetryCollectorConfig provides the providers the OpenTelemetry collector will use.
+type OpenTelemetryCollectorConfig struct {
+	// MeterProvider creates the instruments. If nil, defaults to otel.GetMeterProvider().
+	MeterProvider metric.MeterProvider
+	// TracerProvider creates the spans. If nil, defaults to otel.GetTracerProvider().
+	TracerProvider trace.TracerProvider
+}
+
+// InitializeOpenTelemetryCollector creates the tracer and instruments of the OpenTelemetry collector, sets
+// the client as the tracer of commands with hystrix.SetTracer, and should be called before any metrics are recorded.
+func InitializeOpenTelemetryCollector(config *OpenTelemetryCollectorConfig) (*OpenTelemetryCollectorClient, error) {
+	meterProvider := config.MeterProvider
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+	tracerProvider := config.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+
+	meter := meterProvider.Meter(otelInstrumentationName)
+	events, err := meter.Int64Counter("hystrix.events",
+		metric.WithDescription("Number of events reported by the executions of a circuit, by event type."))
+	if err != nil {
+		return nil, err
+	}
+	runDuration, err := meter.Float64Histogram("hystrix.run.duration",
+		metric.WithDescription("How long the run function of a circuit took."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	totalDuration, err := meter.Float64Histogram("hystrix.total.duration",
+		metric.WithDescription("How long the executions of a circuit took, including waiting for a ticket and the fallback."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &OpenTelemetryCollectorClient{
+		tracer:        tracerProvider.Tracer(otelInstrumentationName),
+		events:        events,
+		runDuration:   runDuration,
+		totalDuration: totalDuration,
+	}
+	hystrix.SetTracer(c)
+
+	return c, nil
+}
+
+// StartExecution starts the span of an execution of the command, as a child of the span carried by the context,
+// so that the spans started by the run and fallback functions are children of it. The span ends with the
+// events of the execution, whether the fallback was used, the circuit state and the time spent queued as attributes.
+func (c *OpenTelemetryCollectorClient) StartExecution(ctx context.Context, name string) (context.Context, func(result metricCollector.ExecutionResult)) {
+	attributes := []attribute.KeyValue{attribute.String("hystrix.command", name)}
+	if circuit, _, err := hystrix.GetCircuit(name); err == nil {
+		attributes = append(attributes, attribute.String("hystrix.command_group", circuit.CommandGroup))
+	}
+	ctx, span := c.tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+
+	return ctx, func(r metricCollector.ExecutionResult) {
+		events := make([]string, len(r.Events))
+		for i, eventType := range r.Events {
+			events[i] = string(eventType)
+		}
+		fallback := r.HasEvent(metricCollector.EventFallbackSuccess) ||
+			r.HasEvent(metricCollector.EventFallbackFailure) ||
+			r.HasEvent(metricCollector.EventFallbackTimeout)
+
+		span.SetAttributes(
+			attribute.StringSlice("hystrix.events", events),
+			attribute.Bool("hystrix.fallback", fallback),
+			attribute.Bool("hystrix.circuit_open", r.CircuitOpen),
+			attribute.Float64("hystrix.queue_duration_ms", float64(r.QueueDuration)/float64(time.Millisecond)),
+		)
+		if r.Error != nil {
+			span.RecordError(r.Error)
+			span.SetStatus(codes.Error, r.Error.Error())
+		}
+		span.End()
+	}
+}
+
+// NewOpenTelemetryCollector creates a collector for a specific circuit.
+func (c *OpenTelemetryCollectorClient) NewOpenTelemetryCollector(name string, commandGroup string) metricCollector.MetricCollectorV2 {
+	return &OpenTelemetryCollector{
+		client: c,
+		attributes: []attribute.KeyValue{
+			attribute.String("hystrix.command", name),
+			attribute.String("hystrix.command_group", commandGroup),
+		},
+	}
+}
+
+// Update increments the counter of every event of the result and records its durations.
+func (oc *OpenTelemetryCollector) Update(r metricCollector.ExecutionResult) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for _, eventType := range r.Events {
+		attributes := append(append([]attribute.KeyValue(nil), oc.attributes...), attribute.String("hystrix.event", string(eventType)))
+		oc.client.events.Add(ctx, 1, metric.WithAttributes(attributes...))
+	}
+	oc.client.runDuration.Record(ctx, r.RunDuration.Seconds(), metric.WithAttributes(oc.attributes...))
+	oc.client.totalDuration.Record(ctx, r.TotalDuration.Seconds(), metric.WithAttributes(oc.attributes...))
+}
+
+// Reset is a noop operation in this collector.
+func (oc *OpenTelemetryCollector) Reset() {}
@@ -0,0 +1,110 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOpenTelemetryCollector(t *testing.T) {
+	Convey("with a collector using local providers", t, func() {
+		spans := tracetest.NewSpanRecorder()
+		tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+		reader := sdkmetric.NewManualReader()
+		meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+		client, err := InitializeOpenTelemetryCollector(&OpenTelemetryCollectorConfig{
+			MeterProvider:  meterProvider,
+			TracerProvider: tracerProvider,
+		})
+		So(err, ShouldBeNil)
+		defer hystrix.SetTracer(nil)
+
+		collector := client.NewOpenTelemetryCollector("foo", "group")
+
+		Convey("a command run within a traced request", func() {
+			defer hystrix.Flush()
+			hystrix.ConfigureCommand("otel", hystrix.CommandConfig{CommandGroup: "group"})
+
+			ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "request")
+			err := hystrix.DoC(ctx, "otel", func(ctx context.Context) error {
+				_, child := tracerProvider.Tracer("test").Start(ctx, "query")
+				child.End()
+				return fmt.Errorf("run_error")
+			}, func(ctx context.Context, err error) error {
+				return nil
+			})
+			So(err, ShouldBeNil)
+			parent.End()
+
+			Convey("is traced as a span wrapping its run function", func() {
+				ended := spans.Ended()
+				So(len(ended), ShouldEqual, 3)
+
+				query, span := ended[0], ended[1]
+				So(query.Name(), ShouldEqual, "query")
+				So(query.Parent().SpanID(), ShouldEqual, span.SpanContext().SpanID())
+				So(span.Name(), ShouldEqual, "otel")
+				So(span.Parent().SpanID(), ShouldEqual, parent.SpanContext().SpanID())
+				So(span.StartTime(), ShouldHappenOnOrBefore, query.StartTime())
+				So(span.Status().Code, ShouldEqual, codes.Error)
+				So(span.Attributes(), ShouldContain, attribute.String("hystrix.command", "otel"))
+				So(span.Attributes(), ShouldContain, attribute.String("hystrix.command_group", "group"))
+				So(span.Attributes(), ShouldContain, attribute.StringSlice("hystrix.events", []string{"failure", "fallback-success"}))
+				So(span.Attributes(), ShouldContain, attribute.Bool("hystrix.fallback", true))
+				So(span.Attributes(), ShouldContain, attribute.Bool("hystrix.circuit_open", false))
+			})
+		})
+
+		Convey("an execution result", func() {
+			collector.Update(metricCollector.ExecutionResult{
+				Events:        []metricCollector.EventType{metricCollector.EventQueued, metricCollector.EventFailure, metricCollector.EventFallbackSuccess},
+				Start:         time.Now(),
+				RunDuration:   10 * time.Millisecond,
+				TotalDuration: 20 * time.Millisecond,
+				QueueDuration: 5 * time.Millisecond,
+				Error:         fmt.Errorf("run_error"),
+				Context:       context.Background(),
+			})
+
+			Convey("records no span", func() {
+				So(spans.Ended(), ShouldBeEmpty)
+			})
+
+			Convey("is recorded in the metrics", func() {
+				var rm metricdata.ResourceMetrics
+				So(reader.Collect(context.Background(), &rm), ShouldBeNil)
+				So(len(rm.ScopeMetrics), ShouldEqual, 1)
+
+				counts := make(map[string]int64)
+				var runDurations uint64
+				for _, m := range rm.ScopeMetrics[0].Metrics {
+					switch data := m.Data.(type) {
+					case metricdata.Sum[int64]:
+						for _, point := range data.DataPoints {
+							event, _ := point.Attributes.Value("hystrix.event")
+							counts[event.AsString()] += point.Value
+						}
+					case metricdata.Histogram[float64]:
+						if m.Name == "hystrix.run.duration" {
+							runDurations = data.DataPoints[0].Count
+						}
+					}
+				}
+				So(counts, ShouldResemble, map[string]int64{"queued": 1, "failure": 1, "fallback-success": 1})
+				So(runDurations, ShouldEqual, 1)
+			})
+		})
+	})
+}
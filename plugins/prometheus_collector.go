@@ -0,0 +1,151 @@
+package plugins
+
+import (
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusCollector fulfills the metricCollector.MetricCollectorV2 interface allowing users to
+// expose circuit stats to Prometheus. To use users must call InitializePrometheusCollector before
+// circuits are started. Then register the NewPrometheusCollector method of the returned client with
+// metricCollector.Registry.RegisterV2(client.NewPrometheusCollector).
+//
+// Every metric is labelled with the name of the circuit and its command group. Prometheus counters
+// only ever go up, so Reset, called when a circuit closes, leaves them untouched.
+type PrometheusCollector struct {
+	client       *PrometheusCollectorClient
+	name         string
+	commandGroup string
+}
+
+// PrometheusCollectorClient holds the metrics shared by the collectors of every circuit.
+type PrometheusCollectorClient struct {
+	events        *prometheus.CounterVec
+	runDuration   *prometheus.HistogramVec
+	totalDuration *prometheus.HistogramVec
+	circuitOpen   *prometheus.GaugeVec
+	poolActive    *prometheus.GaugeVec
+	poolQueued    *prometheus.GaugeVec
+}
+
+// PrometheusCollectorConfig provides configuration for the metrics registered by the Prometheus collector.
+type PrometheusCollectorConfig struct {
+	// Registerer the metrics are registered with. If nil, defaults to prometheus.DefaultRegisterer.
+	Registerer prometheus.Registerer
+	// Namespace is prepended to the names of all metrics, such as "myapp" for "myapp_hystrix_events_total".
+	Namespace string
+	// DurationBuckets are the upper bounds of the duration histograms, in seconds. If nil, defaults to prometheus.DefBuckets.
+	DurationBuckets []float64
+}
+
+// InitializePrometheusCollector registers the metrics of the Prometheus collector
+// and should be called before any metrics are recorded.
+//
+// It returns an error when the metrics were already registered with the Registerer, in which case none of
+// them is left registered.
+func InitializePrometheusCollector(config *PrometheusCollectorConfig) (*PrometheusCollectorClient, error) {
+	registerer := config.Registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	buckets := config.DurationBuckets
+	if buckets == nil {
+		buckets = prometheus.DefBuckets
+	}
+
+	labels := []string{"circuit", "command_group"}
+	c := &PrometheusCollectorClient{
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: "hystrix",
+			Name:      "events_total",
+			Help:      "Number of events reported by the executions of a circuit, by event type.",
+		}, append(labels, "event")),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: "hystrix",
+			Name:      "run_duration_seconds",
+			Help:      "How long the run function of a circuit took.",
+			Buckets:   buckets,
+		}, labels),
+		totalDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: "hystrix",
+			Name:      "total_duration_seconds",
+			Help:      "How long the executions of a circuit took, including waiting for a ticket and the fallback.",
+			Buckets:   buckets,
+		}, labels),
+		circuitOpen: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Subsystem: "hystrix",
+			Name:      "circuit_open",
+			Help:      "Whether the circuit is open.",
+		}, labels),
+		poolActive: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Subsystem: "hystrix",
+			Name:      "pool_active",
+			Help:      "Number of executions of the circuit holding a ticket.",
+		}, labels),
+		poolQueued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Subsystem: "hystrix",
+			Name:      "pool_queued",
+			Help:      "Number of executions of the circuit waiting for a ticket.",
+		}, labels),
+	}
+
+	var registered []prometheus.Collector
+	for _, collector := range []prometheus.Collector{c.events, c.runDuration, c.totalDuration, c.circuitOpen, c.poolActive, c.poolQueued} {
+		if err := registerer.Register(collector); err != nil {
+			// leave the registerer as it was, so that initializing the collector can be tried again
+			for _, r := range registered {
+				registerer.Unregister(r)
+			}
+			return nil, err
+		}
+		registered = append(registered, collector)
+	}
+
+	return c, nil
+}
+
+// NewPrometheusCollector creates a collector for a specific circuit.
+func (c *PrometheusCollectorClient) NewPrometheusCollector(name string, commandGroup string) metricCollector.MetricCollectorV2 {
+	return &PrometheusCollector{
+		client:       c,
+		name:         name,
+		commandGroup: commandGroup,
+	}
+}
+
+// Update increments the counter of every event of the result, observes its durations and sets the gauges.
+func (pc *PrometheusCollector) Update(r metricCollector.ExecutionResult) {
+	for _, eventType := range r.Events {
+		pc.client.events.WithLabelValues(pc.name, pc.commandGroup, string(eventType)).Inc()
+	}
+
+	pc.client.runDuration.WithLabelValues(pc.name, pc.commandGroup).Observe(r.RunDuration.Seconds())
+	pc.client.totalDuration.WithLabelValues(pc.name, pc.commandGroup).Observe(r.TotalDuration.Seconds())
+
+	pc.UpdateCircuitState(metricCollector.CircuitState{
+		Open:         r.CircuitOpen,
+		ActiveCount:  r.ActiveCount,
+		WaitingCount: r.WaitingCount,
+	})
+}
+
+// UpdateCircuitState sets the circuit open and pool gauges, so that they do not go stale between executions.
+func (pc *PrometheusCollector) UpdateCircuitState(state metricCollector.CircuitState) {
+	open := float64(0)
+	if state.Open {
+		open = 1
+	}
+	pc.client.circuitOpen.WithLabelValues(pc.name, pc.commandGroup).Set(open)
+	pc.client.poolActive.WithLabelValues(pc.name, pc.commandGroup).Set(float64(state.ActiveCount))
+	pc.client.poolQueued.WithLabelValues(pc.name, pc.commandGroup).Set(float64(state.WaitingCount))
+}
+
+// Reset is a noop operation in this collector.
+func (pc *PrometheusCollector) Reset() {}
@@ -0,0 +1,103 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPrometheusCollector(t *testing.T) {
+	Convey("with a collector registered with a local registry", t, func() {
+		registry := prometheus.NewRegistry()
+		client, err := InitializePrometheusCollector(&PrometheusCollectorConfig{
+			Registerer: registry,
+			Namespace:  "test",
+		})
+		So(err, ShouldBeNil)
+
+		collector := client.NewPrometheusCollector("foo", "group")
+
+		Convey("an execution result is exposed in the scrape", func() {
+			collector.Update(metricCollector.ExecutionResult{
+				Events:        []metricCollector.EventType{metricCollector.EventFailure, metricCollector.EventFallbackSuccess},
+				RunDuration:   10 * time.Millisecond,
+				TotalDuration: 20 * time.Millisecond,
+				CircuitOpen:   true,
+				ActiveCount:   2,
+				WaitingCount:  1,
+			})
+			collector.Update(metricCollector.ExecutionResult{
+				Events:        []metricCollector.EventType{metricCollector.EventSuccess},
+				RunDuration:   30 * time.Millisecond,
+				TotalDuration: 30 * time.Millisecond,
+			})
+
+			expected := `
+# HELP test_hystrix_events_total Number of events reported by the executions of a circuit, by event type.
+# TYPE test_hystrix_events_total counter
+test_hystrix_events_total{circuit="foo",command_group="group",event="failure"} 1
+test_hystrix_events_total{circuit="foo",command_group="group",event="fallback-success"} 1
+test_hystrix_events_total{circuit="foo",command_group="group",event="success"} 1
+# HELP test_hystrix_circuit_open Whether the circuit is open.
+# TYPE test_hystrix_circuit_open gauge
+test_hystrix_circuit_open{circuit="foo",command_group="group"} 0
+# HELP test_hystrix_pool_active Number of executions of the circuit holding a ticket.
+# TYPE test_hystrix_pool_active gauge
+test_hystrix_pool_active{circuit="foo",command_group="group"} 0
+`
+			err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+				"test_hystrix_events_total", "test_hystrix_circuit_open", "test_hystrix_pool_active")
+			So(err, ShouldBeNil)
+
+			families, err := registry.Gather()
+			So(err, ShouldBeNil)
+			for _, family := range families {
+				if family.GetName() == "test_hystrix_run_duration_seconds" {
+					histogram := family.GetMetric()[0].GetHistogram()
+					So(histogram.GetSampleCount(), ShouldEqual, 2)
+					So(histogram.GetSampleSum(), ShouldAlmostEqual, 0.04)
+				}
+			}
+			count, err := testutil.GatherAndCount(registry, "test_hystrix_total_duration_seconds")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+		})
+
+		Convey("initializing it again with the same registry fails", func() {
+			_, err := InitializePrometheusCollector(&PrometheusCollectorConfig{
+				Registerer: registry,
+				Namespace:  "test",
+			})
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("with a local registry already holding one of the metrics", t, func() {
+		registry := prometheus.NewRegistry()
+		conflict := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: "test", Subsystem: "hystrix", Name: "circuit_open"})
+		So(registry.Register(conflict), ShouldBeNil)
+
+		_, err := InitializePrometheusCollector(&PrometheusCollectorConfig{
+			Registerer: registry,
+			Namespace:  "test",
+		})
+
+		Convey("initializing the collector fails", func() {
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("the metrics registered before the failure are unregistered", func() {
+			events := prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: "test",
+				Subsystem: "hystrix",
+				Name:      "events_total",
+				Help:      "Number of events reported by the executions of a circuit, by event type.",
+			}, []string{"circuit", "command_group", "event"})
+			So(registry.Register(events), ShouldBeNil)
+		})
+	})
+}
@@ -2,6 +2,8 @@ package plugins
 
 import (
 	"log"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,14 +31,34 @@ type StatsdCollector struct {
 	fallbackFailuresPrefix  string
 	totalDurationPrefix     string
 	runDurationPrefix       string
+	poolActivePrefix        string
+	poolWaitingPrefix       string
+	dogStatsdTags           string
 	sampleRate              float32
 }
 
 type StatsdCollectorClient struct {
 	client     statsd.Statter
 	sampleRate float32
+	tagFormat  StatsdTagFormat
+	tags       []string
 }
 
+// StatsdTagFormat selects how the circuit, its command group and the static tags are sent to the Statsd server.
+type StatsdTagFormat int
+
+const (
+	// NoStatsdTags encodes the command group and the circuit in the metric name.
+	NoStatsdTags StatsdTagFormat = iota
+	// DogStatsdTags appends the tags to the metric in the DogStatsD format, such as "hystrix.attempts:1|c|#circuit:foo,group:bar".
+	// The sample rate, if any, follows the tags, which DogStatsD accepts.
+	DogStatsdTags
+	// InfluxStatsdTags appends the tags to the metric name in the InfluxDB format, such as "hystrix.attempts,circuit=foo,group=bar:1|c".
+	InfluxStatsdTags
+	// GraphiteStatsdTags appends the tags to the metric name in the Graphite format, such as "hystrix.attempts;circuit=foo;group=bar:1|c".
+	GraphiteStatsdTags
+)
+
 // https://github.com/etsy/statsd/blob/master/docs/metric_types.md#multi-metric-packets
 const (
 	WANStatsdFlushBytes     = 512
@@ -54,6 +76,11 @@ type StatsdCollectorConfig struct {
 	SampleRate float32
 	// FlushBytes sets message size for statsd packets. If 0, defaults to LANFlushSize.
 	FlushBytes int
+	// TagFormat sets the dialect used to send the circuit and its command group as tags, so that metric names
+	// stay the same for every circuit. If 0, defaults to NoStatsdTags.
+	TagFormat StatsdTagFormat
+	// Tags are static tags sent with every metric, such as the name of the service. They are ignored with NoStatsdTags.
+	Tags map[string]string
 }
 
 // InitializeStatsdCollector creates the connection to the Statsd server
@@ -76,37 +103,66 @@ func InitializeStatsdCollector(config *StatsdCollectorConfig) (*StatsdCollectorC
 		log.Printf("Could not initiale buffered client: %s. Falling back to a Noop Statsd client", err)
 		c, _ = statsd.NewNoopClient()
 	}
+	tagKeys := make([]string, 0, len(config.Tags))
+	for key := range config.Tags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	tags := make([]string, 0, 2*len(tagKeys))
+	for _, key := range tagKeys {
+		tags = append(tags, key, config.Tags[key])
+	}
+
 	return &StatsdCollectorClient{
 		client:     c,
 		sampleRate: sampleRate,
+		tagFormat:  config.TagFormat,
+		tags:       tags,
 	}, err
 }
 
 // NewStatsdCollector creates a collector for a specific circuit. The
 // prefix given to this circuit will be {config.Prefix}.{command_group}.{circuit_name}.{metric}.
 // Circuits with "/" in their names will have them replaced with ".".
+//
+// When a TagFormat is configured, the metric names are {config.Prefix}.{metric} instead, and the circuit
+// and its command group are sent as the "circuit" and "group" tags, followed by the static tags.
 func (s *StatsdCollectorClient) NewStatsdCollector(name string, commandGroup string) metricCollector.MetricCollector {
 	if s.client == nil {
 		log.Fatalf("Statsd client must be initialized before circuits are created.")
 	}
-	name = formatStatsdString(name)
-	commandGroup = formatStatsdString(commandGroup)
+
+	prefix := formatStatsdString(commandGroup) + "." + formatStatsdString(name) + "."
+	suffix := ""
+	dogStatsdTags := ""
+	if s.tagFormat != NoStatsdTags {
+		prefix = ""
+		tags := formatStatsdTags(s.tagFormat, append([]string{"circuit", name, "group", commandGroup}, s.tags...))
+		if s.tagFormat == DogStatsdTags {
+			dogStatsdTags = tags
+		} else {
+			suffix = tags
+		}
+	}
 
 	return &StatsdCollector{
 		client:                  s.client,
-		circuitOpenPrefix:       commandGroup + "." + name + ".circuitOpen",
-		attemptsPrefix:          commandGroup + "." + name + ".attempts",
-		errorsPrefix:            commandGroup + "." + name + ".errors",
-		queueSizePrefix:         commandGroup + "." + name + ".queueLength",
-		successesPrefix:         commandGroup + "." + name + ".successes",
-		failuresPrefix:          commandGroup + "." + name + ".failures",
-		rejectsPrefix:           commandGroup + "." + name + ".rejects",
-		shortCircuitsPrefix:     commandGroup + "." + name + ".shortCircuits",
-		timeoutsPrefix:          commandGroup + "." + name + ".timeouts",
-		fallbackSuccessesPrefix: commandGroup + "." + name + ".fallbackSuccesses",
-		fallbackFailuresPrefix:  commandGroup + "." + name + ".fallbackFailures",
-		totalDurationPrefix:     commandGroup + "." + name + ".totalDuration",
-		runDurationPrefix:       commandGroup + "." + name + ".runDuration",
+		circuitOpenPrefix:       prefix + "circuitOpen" + suffix,
+		attemptsPrefix:          prefix + "attempts" + suffix,
+		errorsPrefix:            prefix + "errors" + suffix,
+		queueSizePrefix:         prefix + "queueLength" + suffix,
+		successesPrefix:         prefix + "successes" + suffix,
+		failuresPrefix:          prefix + "failures" + suffix,
+		rejectsPrefix:           prefix + "rejects" + suffix,
+		shortCircuitsPrefix:     prefix + "shortCircuits" + suffix,
+		timeoutsPrefix:          prefix + "timeouts" + suffix,
+		fallbackSuccessesPrefix: prefix + "fallbackSuccesses" + suffix,
+		fallbackFailuresPrefix:  prefix + "fallbackFailures" + suffix,
+		totalDurationPrefix:     prefix + "totalDuration" + suffix,
+		runDurationPrefix:       prefix + "runDuration" + suffix,
+		poolActivePrefix:        prefix + "poolActive" + suffix,
+		poolWaitingPrefix:       prefix + "poolWaiting" + suffix,
+		dogStatsdTags:           dogStatsdTags,
 		sampleRate:              s.sampleRate,
 	}
 }
@@ -118,22 +174,62 @@ func formatStatsdString(name string) string {
 	return name
 }
 
+// statsdTagEscaper replaces the characters which separate tags, their names and values, or the parts of a metric,
+// in any of the tag formats.
+var statsdTagEscaper = strings.NewReplacer(",", "-", ";", "-", "=", "-", ":", "-", "|", "-", "#", "-", " ", "-")
+
+// formatStatsdTags renders the tags, given as name and value pairs, in the tag format.
+func formatStatsdTags(format StatsdTagFormat, tags []string) string {
+	prefix, separator, assign := ",", ",", "="
+	switch format {
+	case DogStatsdTags:
+		prefix, assign = "|#", ":"
+	case GraphiteStatsdTags:
+		prefix, separator = ";", ";"
+	}
+
+	formatted := prefix
+	for i := 0; i < len(tags); i += 2 {
+		if i > 0 {
+			formatted += separator
+		}
+		formatted += statsdTagEscaper.Replace(tags[i]) + assign + statsdTagEscaper.Replace(tags[i+1])
+	}
+	return formatted
+}
+
 func (g *StatsdCollector) setGauge(prefix string, value int64) {
-	err := g.client.Gauge(prefix, value, g.sampleRate)
+	var err error
+	if g.dogStatsdTags != "" {
+		err = g.client.Raw(prefix, strconv.FormatInt(value, 10)+"|g"+g.dogStatsdTags, g.sampleRate)
+	} else {
+		err = g.client.Gauge(prefix, value, g.sampleRate)
+	}
 	if err != nil {
 		log.Printf("Error sending statsd metrics %s", prefix)
 	}
 }
 
 func (g *StatsdCollector) incrementCounterMetric(prefix string) {
-	err := g.client.Inc(prefix, 1, g.sampleRate)
+	var err error
+	if g.dogStatsdTags != "" {
+		err = g.client.Raw(prefix, "1|c"+g.dogStatsdTags, g.sampleRate)
+	} else {
+		err = g.client.Inc(prefix, 1, g.sampleRate)
+	}
 	if err != nil {
 		log.Printf("Error sending statsd metrics %s", prefix)
 	}
 }
 
 func (g *StatsdCollector) updateTimerMetric(prefix string, dur time.Duration) {
-	err := g.client.TimingDuration(prefix, dur, g.sampleRate)
+	var err error
+	if g.dogStatsdTags != "" {
+		ms := strconv.FormatFloat(float64(dur)/float64(time.Millisecond), 'f', -1, 64)
+		err = g.client.Raw(prefix, ms+"|ms"+g.dogStatsdTags, g.sampleRate)
+	} else {
+		err = g.client.TimingDuration(prefix, dur, g.sampleRate)
+	}
 	if err != nil {
 		log.Printf("Error sending statsd metrics %s", prefix)
 	}
@@ -163,7 +259,6 @@ func (g *StatsdCollector) IncrementErrors() {
 // IncrementSuccesses increments the number of requests that succeed.
 // This registers as a counter in the Statsd collector.
 func (g *StatsdCollector) IncrementSuccesses() {
-	g.setGauge(g.circuitOpenPrefix, 0)
 	g.incrementCounterMetric(g.successesPrefix)
 
 }
@@ -183,7 +278,6 @@ func (g *StatsdCollector) IncrementRejects() {
 // IncrementShortCircuits increments the number of requests that short circuited due to the circuit being open.
 // This registers as a counter in the Statsd collector.
 func (g *StatsdCollector) IncrementShortCircuits() {
-	g.setGauge(g.circuitOpenPrefix, 1)
 	g.incrementCounterMetric(g.shortCircuitsPrefix)
 }
 
@@ -217,5 +311,17 @@ func (g *StatsdCollector) UpdateRunDuration(runDuration time.Duration) {
 	g.updateTimerMetric(g.runDurationPrefix, runDuration)
 }
 
+// UpdateCircuitState records whether the circuit is open and the number of executions holding and waiting for a ticket.
+// These register as gauges in the Statsd collector.
+func (g *StatsdCollector) UpdateCircuitState(state metricCollector.CircuitState) {
+	open := int64(0)
+	if state.Open {
+		open = 1
+	}
+	g.setGauge(g.circuitOpenPrefix, open)
+	g.setGauge(g.poolActivePrefix, int64(state.ActiveCount))
+	g.setGauge(g.poolWaitingPrefix, int64(state.WaitingCount))
+}
+
 // Reset is a noop operation in this collector.
 func (g *StatsdCollector) Reset() {}
@@ -2,9 +2,11 @@ package plugins
 
 import (
 	"testing"
+	"time"
 
 	"sync/atomic"
 
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
 	"github.com/myteksi/hystrix-go/plugins/mocks"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/mock"
@@ -75,3 +77,95 @@ func TestCommandGroup(t *testing.T) {
 	})
 
 }
+
+func TestStatsdCircuitState(t *testing.T) {
+	mockStatsd := &mocks.Statter{}
+	client := &StatsdCollectorClient{client: mockStatsd, sampleRate: 1}
+	collector := client.NewStatsdCollector("commandName1", "commandGroup1").(*StatsdCollector)
+	mockStatsd.On("Gauge", "commandGroup1.commandName1.circuitOpen", int64(1), float32(1)).Return(nil)
+	mockStatsd.On("Gauge", "commandGroup1.commandName1.poolActive", int64(3), float32(1)).Return(nil)
+	mockStatsd.On("Gauge", "commandGroup1.commandName1.poolWaiting", int64(2), float32(1)).Return(nil)
+
+	Convey("update circuit state", t, func() {
+		collector.UpdateCircuitState(metricCollector.CircuitState{Open: true, ActiveCount: 3, WaitingCount: 2, MaxConcurrentRequests: 10})
+
+		So(mockStatsd.AssertNumberOfCalls(t, "Gauge", 3), ShouldBeTrue)
+		mockStatsd.AssertExpectations(t)
+	})
+
+	Convey("short circuits leave the circuit open gauge to the circuit state", t, func() {
+		mockStatsd.On("Inc", "commandGroup1.commandName1.shortCircuits", int64(1), float32(1)).Return(nil)
+		mockStatsd.On("Inc", "commandGroup1.commandName1.successes", int64(1), float32(1)).Return(nil)
+		collector.IncrementShortCircuits()
+		collector.IncrementSuccesses()
+
+		So(mockStatsd.AssertNumberOfCalls(t, "Gauge", 3), ShouldBeTrue)
+	})
+}
+
+func TestStatsdTags(t *testing.T) {
+	newTaggedCollector := func(format StatsdTagFormat) (*mocks.Statter, *StatsdCollector) {
+		mockStatsd := &mocks.Statter{}
+		client := &StatsdCollectorClient{
+			client:     mockStatsd,
+			sampleRate: 1,
+			tagFormat:  format,
+			tags:       []string{"env", "prod", "region", "us"},
+		}
+		return mockStatsd, client.NewStatsdCollector("foo/bar", "group,1").(*StatsdCollector)
+	}
+
+	Convey("with DogStatsD tags", t, func() {
+		mockStatsd, collector := newTaggedCollector(DogStatsdTags)
+
+		Convey("the metric names are constant and the tags follow the value", func() {
+			tags := "|#circuit:foo/bar,group:group-1,env:prod,region:us"
+			mockStatsd.On("Raw", "attempts", "1|c"+tags, float32(1)).Return(nil).Once()
+			mockStatsd.On("Raw", "circuitOpen", "1|g"+tags, float32(1)).Return(nil).Once()
+			mockStatsd.On("Raw", "poolActive", "0|g"+tags, float32(1)).Return(nil).Once()
+			mockStatsd.On("Raw", "poolWaiting", "0|g"+tags, float32(1)).Return(nil).Once()
+			mockStatsd.On("Raw", "runDuration", "1.5|ms"+tags, float32(1)).Return(nil).Once()
+
+			collector.IncrementAttempts()
+			collector.UpdateCircuitState(metricCollector.CircuitState{Open: true})
+			collector.UpdateRunDuration(1500 * time.Microsecond)
+
+			So(mockStatsd.AssertCalled(t, "Raw", "attempts", "1|c"+tags, float32(1)), ShouldBeTrue)
+			So(mockStatsd.AssertCalled(t, "Raw", "circuitOpen", "1|g"+tags, float32(1)), ShouldBeTrue)
+			So(mockStatsd.AssertCalled(t, "Raw", "runDuration", "1.5|ms"+tags, float32(1)), ShouldBeTrue)
+		})
+	})
+
+	Convey("with InfluxDB tags", t, func() {
+		_, collector := newTaggedCollector(InfluxStatsdTags)
+
+		Convey("the tags are appended to the metric name", func() {
+			So(collector.attemptsPrefix, ShouldEqual, "attempts,circuit=foo/bar,group=group-1,env=prod,region=us")
+		})
+	})
+
+	Convey("with Graphite tags", t, func() {
+		mockStatsd, collector := newTaggedCollector(GraphiteStatsdTags)
+
+		Convey("the tags are appended to the metric name", func() {
+			metric := "attempts;circuit=foo/bar;group=group-1;env=prod;region=us"
+			mockStatsd.On("Inc", metric, int64(1), float32(1)).Return(nil).Once()
+
+			collector.IncrementAttempts()
+
+			So(mockStatsd.AssertCalled(t, "Inc", metric, int64(1), float32(1)), ShouldBeTrue)
+		})
+	})
+
+	Convey("static tags from the config are sorted by name", t, func() {
+		client, err := InitializeStatsdCollector(&StatsdCollectorConfig{
+			StatsdAddr: "localhost:8125",
+			TagFormat:  InfluxStatsdTags,
+			Tags:       map[string]string{"region": "us", "env": "prod"},
+		})
+		So(err, ShouldBeNil)
+
+		collector := client.NewStatsdCollector("foo", "group").(*StatsdCollector)
+		So(collector.attemptsPrefix, ShouldEqual, "attempts,circuit=foo,group=group,env=prod,region=us")
+	})
+}
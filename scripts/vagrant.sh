@@ -18,5 +18,7 @@ go get github.com/smartystreets/goconvey/convey
 go get github.com/cactus/go-statsd-client/statsd
 go get github.com/rcrowley/go-metrics
 go get github.com/DataDog/datadog-go/statsd
+go get github.com/prometheus/client_golang/prometheus
+go get go.opentelemetry.io/otel
 
 chown -R vagrant:vagrant /go